@@ -4,19 +4,25 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 
 	"CleanMyEmail/internal/account"
+	"CleanMyEmail/internal/archive"
 	"CleanMyEmail/internal/db"
+	"CleanMyEmail/internal/email/analyzer"
 	"CleanMyEmail/internal/email/cleaner"
 	"CleanMyEmail/internal/email/folder"
 	"CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/email/restorer"
+	jobpkg "CleanMyEmail/internal/job"
 	"CleanMyEmail/internal/model"
 	"CleanMyEmail/internal/oauth2"
 	"CleanMyEmail/internal/proxy"
+	"CleanMyEmail/internal/scheduler"
 	"CleanMyEmail/internal/service"
 )
 
@@ -31,11 +37,21 @@ type OAuth2Session struct {
 
 // App struct
 type App struct {
-	ctx            context.Context
-	accountService *account.Service
-	historyService *service.HistoryService
-	poolManager    *imap.PoolManager // 连接池管理器
-	currentCleaner *cleaner.Cleaner
+	ctx             context.Context
+	accountService  *account.Service
+	historyService  *service.HistoryService
+	auditService    *service.AuditService
+	presetService   *service.PresetService
+	previewService  *service.PreviewService
+	planService     *service.PlanService
+	analysisService *service.AnalysisService
+	poolManager     *imap.PoolManager // 连接池管理器
+	scheduler       *scheduler.Scheduler
+	jobManager      *jobpkg.Manager // 清理任务管理器，按任务 ID 管理并发执行的清理
+	// 正在执行的归档恢复和分析（按账号 ID），同一账号同时只能各有一个
+	restorers map[int64]*restorer.Restorer
+	analyzers map[int64]*analyzer.Analyzer
+	runnersMu sync.Mutex
 	// OAuth2 回调服务器（共享，支持多会话）
 	callbackServer *oauth2.CallbackServer
 	// OAuth2 会话管理（使用 state 作为 key）
@@ -45,12 +61,25 @@ type App struct {
 
 // NewApp creates a new App application struct
 func NewApp() *App {
+	accountService := account.NewService()
+	historyService := service.NewHistoryService()
+	poolManager := imap.NewPoolManager()
+	jobManager := jobpkg.NewManager()
 	return &App{
-		accountService: account.NewService(),
-		historyService: service.NewHistoryService(),
-		poolManager:    imap.NewPoolManager(),
-		callbackServer: oauth2.NewCallbackServer(),
-		oauth2Sessions: make(map[string]*OAuth2Session),
+		accountService:  accountService,
+		historyService:  historyService,
+		auditService:    service.NewAuditService(),
+		presetService:   service.NewPresetService(historyService),
+		previewService:  service.NewPreviewService(),
+		planService:     service.NewPlanService(),
+		analysisService: service.NewAnalysisService(),
+		poolManager:     poolManager,
+		jobManager:      jobManager,
+		scheduler:       scheduler.NewScheduler(accountService, historyService, poolManager, jobManager),
+		restorers:       make(map[int64]*restorer.Restorer),
+		analyzers:       make(map[int64]*analyzer.Analyzer),
+		callbackServer:  oauth2.NewCallbackServer(),
+		oauth2Sessions:  make(map[string]*OAuth2Session),
 	}
 }
 
@@ -61,6 +90,12 @@ func (a *App) startup(ctx context.Context) {
 	if _, err := db.GetDB(); err != nil {
 		wailsRuntime.LogError(ctx, fmt.Sprintf("初始化数据库失败: %v", err))
 	}
+	// 上次退出时仍在运行的清理任务标记为中断，等待用户选择继续或结束
+	if n, err := a.historyService.MarkInterrupted(); err != nil {
+		log.Printf("[WARN] 检查中断的清理任务失败: %v", err)
+	} else if n > 0 {
+		log.Printf("[INFO] 发现 %d 个中断的清理任务", n)
+	}
 	// 加载代理设置
 	if proxySettings, err := db.GetProxySettings(); err == nil && proxySettings != nil {
 		proxy.SetGlobalProxy(proxySettings)
@@ -68,6 +103,15 @@ func (a *App) startup(ctx context.Context) {
 			log.Printf("[INFO] 已加载代理设置: %s", proxySettings.GetURL())
 		}
 	}
+	// 清理任务事件（job:start / job:progress / job:complete）
+	a.jobManager.SetEventHandler(func(event string, data any) {
+		wailsRuntime.EventsEmit(a.ctx, event, data)
+	})
+	// 启动定时清理
+	a.scheduler.SetEventHandler(func(event string, data *model.ScheduleRunEvent) {
+		wailsRuntime.EventsEmit(a.ctx, event, data)
+	})
+	a.scheduler.Start()
 }
 
 // shutdown is called when the app is closing
@@ -76,6 +120,10 @@ func (a *App) shutdown(ctx context.Context) {
 	if a.callbackServer != nil {
 		a.callbackServer.ForceStop()
 	}
+	// 停止定时清理（需在关闭连接池之前）
+	if a.scheduler != nil {
+		a.scheduler.Stop()
+	}
 	// 关闭连接池管理器
 	if a.poolManager != nil {
 		a.poolManager.Close()
@@ -181,8 +229,9 @@ func (a *App) GetFolderTree(accountID int64) ([]*model.FolderTreeNode, error) {
 		return nil, fmt.Errorf("连接邮箱失败: %w", err)
 	}
 
-	// 检查是否支持 LIST-STATUS
+	// 检查是否支持 LIST-STATUS 和 STATUS=SIZE
 	supportsListStatus := imap.SupportsListStatus(conn.Client())
+	supportsStatusSize := imap.SupportsStatusSize(conn.Client())
 
 	folders, err := imap.ListMailboxes(conn.Client())
 	if err != nil {
@@ -197,13 +246,19 @@ func (a *App) GetFolderTree(accountID int64) ([]*model.FolderTreeNode, error) {
 
 	tree := folder.BuildFolderTree(folders)
 
-	// 如果不支持 LIST-STATUS，异步获取邮件数量
-	if !supportsListStatus {
+	// 如果不支持 LIST-STATUS，异步获取邮件数量；不支持 STATUS=SIZE 时再逐封累加计算占用空间
+	if !supportsListStatus || !supportsStatusSize {
 		go func() {
 			defer conn.Release()
-			imap.FetchFolderStatus(conn.Client(), folders, func(update imap.FolderStatusUpdate) {
+			emit := func(update imap.FolderStatusUpdate) {
 				wailsRuntime.EventsEmit(a.ctx, "folder:status", update)
-			})
+			}
+			if !supportsListStatus {
+				imap.FetchFolderStatus(conn.Client(), folders, emit)
+			}
+			if !supportsStatusSize {
+				imap.FetchFolderSizes(conn.Client(), folders, emit)
+			}
 		}()
 	} else {
 		conn.Release()
@@ -214,17 +269,20 @@ func (a *App) GetFolderTree(accountID int64) ([]*model.FolderTreeNode, error) {
 
 // ==================== 邮件清理 ====================
 
-// StartClean 开始清理
-func (a *App) StartClean(req model.CleanRequest) error {
-	cfg, err := a.accountService.GetConnectConfig(req.AccountID)
-	if err != nil {
-		return err
-	}
-
+// StartClean 开始清理，返回清理任务；不同账号的清理可以同时进行，同一账号同时只能有一个删除类任务
+func (a *App) StartClean(req model.CleanRequest) (*model.CleanJob, error) {
 	// 获取账号邮箱
 	acc, err := a.accountService.Get(req.AccountID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := a.jobManager.CheckAvailable(req.AccountID, !req.PreviewOnly); err != nil {
+		return nil, err
+	}
+
+	// 相对日期按用户时区解析，表达式和解析后的日期都记录到历史
+	if err := req.ResolveDates(time.Now().In(db.GetLocation())); err != nil {
+		return nil, err
 	}
 
 	// 创建历史记录
@@ -233,52 +291,568 @@ func (a *App) StartClean(req model.CleanRequest) error {
 		log.Printf("[WARN] 创建历史记录失败: %v", err)
 	}
 
+	// 删除前备份：预先确定归档目录并记录到历史，确保每次删除都有可恢复的副本
+	var archiveDir string
+	if req.BackupBeforeDelete && !req.PreviewOnly {
+		archiveDir = archive.NewRunDir(req.AccountID)
+		if historyID > 0 {
+			if err := a.historyService.SetArchivePath(historyID, archiveDir); err != nil {
+				log.Printf("[WARN] 记录归档目录失败: %v", err)
+			}
+		}
+	}
+
+	job := &model.CleanJob{Kind: model.JobKindClean, AccountEmail: acc.Email, HistoryID: historyID}
+	return a.runClean(job, &req, archiveDir, nil)
+}
+
+// runClean 创建清理器并作为清理任务异步执行（新任务、继续中断的任务和执行清理计划共用）
+// plan 不为空时只删除计划中的邮件
+func (a *App) runClean(job *model.CleanJob, req *model.CleanRequest, archiveDir string, plan *model.CleanPlan) (*model.CleanJob, error) {
+	historyID := job.HistoryID
+	if req.PlanOnly && plan == nil {
+		err := fmt.Errorf("该清理请求只能按清理计划执行")
+		a.abortHistory(job, err)
+		return nil, err
+	}
+	cfg, err := a.accountService.GetConnectConfig(req.AccountID)
+	if err != nil {
+		a.abortHistory(job, err)
+		return nil, err
+	}
+
 	// 使用连接池管理器获取连接池
 	concurrency := req.GetMaxConcurrency()
 	pool := a.poolManager.GetPool(req.AccountID, cfg, &imap.PoolOptions{
 		MaxSize:     concurrency,
 		IdleTimeout: 5 * time.Minute,
 	})
-	currentCleaner := cleaner.NewCleaner(pool)
-	a.currentCleaner = currentCleaner
+	c := cleaner.NewCleaner(pool)
+	c.SetArchiveDir(archiveDir)
+	if plan != nil {
+		c.SetPlan(plan)
+	}
+	if historyID > 0 && !req.PreviewOnly {
+		c.EnableCheckpoint(historyID, a.historyService)
+		c.EnableAudit(historyID, job.AccountEmail, a.auditService)
+	}
+
+	job.AccountID = req.AccountID
+	job.PreviewOnly = req.PreviewOnly
+	started, err := a.jobManager.Start(&jobpkg.Task{
+		Job:     job,
+		Cleaner: c,
+		Request: req,
+		OnProgress: func(progress *model.CleanProgress) {
+			wailsRuntime.EventsEmit(a.ctx, "clean:progress", progress)
+		},
+		OnDone: func(result *model.CleanResult, err error) {
+			if err != nil {
+				// 更新历史记录为失败
+				if historyID > 0 {
+					a.historyService.UpdateHistory(historyID, 0, 0, "failed", err.Error(), 0)
+					a.historyService.DeleteCheckpoints(historyID)
+				}
+				wailsRuntime.EventsEmit(a.ctx, "clean:error", &model.CleanErrorEvent{
+					JobID:     job.ID,
+					AccountID: job.AccountID,
+					Error:     err.Error(),
+				})
+				return
+			}
+			// 更新历史记录为完成
+			if historyID > 0 {
+				matchedCount := 0
+				for _, stat := range result.FolderStats {
+					matchedCount += stat.MatchedCount
+				}
+				a.historyService.UpdateHistory(historyID, matchedCount, result.TotalDeleted, result.Status, "", result.Duration)
+				// 任务正常结束，检查点只在应用中断时才需要保留
+				a.historyService.DeleteCheckpoints(historyID)
+			}
+			// 保存预览的邮件明细，供前端分页查看；预览完整完成时生成清理计划
+			if req.PreviewOnly {
+				a.previewService.Save(req.AccountID, historyID, c.PreviewMessages())
+				if result.Status == "completed" {
+					a.createPlan(req, historyID, c.PlanFolders(), result)
+				}
+			}
+			wailsRuntime.EventsEmit(a.ctx, "clean:complete", result)
+		},
+	})
+	if err != nil {
+		a.abortHistory(job, err)
+		return nil, err
+	}
+	return started, nil
+}
+
+// abortHistory 清理任务未能开始时更新历史记录：继续中断的任务恢复为中断状态，其余标记为失败
+func (a *App) abortHistory(job *model.CleanJob, err error) {
+	if job.HistoryID <= 0 {
+		return
+	}
+	if job.Kind == model.JobKindResume {
+		a.historyService.SetHistoryStatus(job.HistoryID, "interrupted")
+		return
+	}
+	a.historyService.UpdateHistory(job.HistoryID, 0, 0, "failed", err.Error(), 0)
+}
+
+// ResolveCleanDates 按用户时区解析请求中的相对日期（OlderThan/Between），返回填好 StartDate/EndDate 的请求，用于界面展示
+func (a *App) ResolveCleanDates(req model.CleanRequest) (*model.CleanRequest, error) {
+	if err := req.ResolveDates(time.Now().In(db.GetLocation())); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// GetPreviewMessages 分页获取账号最近一次预览中符合条件的邮件，支持按日期、大小、发件人、主题、文件夹排序
+func (a *App) GetPreviewMessages(q model.PreviewQuery) (*model.PreviewPage, error) {
+	return a.previewService.Page(q)
+}
+
+// ExecutePlan 执行预览生成的清理计划，只删除预览时确定的邮件
+// 计划已执行、已过期，或任一文件夹的 UIDVALIDITY 与预览时不一致时拒绝执行
+func (a *App) ExecutePlan(planID int64) (*model.CleanJob, error) {
+	plan, err := a.planService.Prepare(planID)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.jobManager.CheckAvailable(plan.AccountID, true); err != nil {
+		return nil, err
+	}
+
+	req := plan.ExecuteRequest()
+	historyID, err := a.historyService.CreateHistory(&req, plan.AccountEmail)
+	if err != nil {
+		log.Printf("[WARN] 创建历史记录失败: %v", err)
+	}
+	if err := a.planService.Claim(planID, historyID); err != nil {
+		if historyID > 0 {
+			a.historyService.UpdateHistory(historyID, 0, 0, "failed", err.Error(), 0)
+		}
+		return nil, err
+	}
+
+	var archiveDir string
+	if req.BackupBeforeDelete {
+		archiveDir = archive.NewRunDir(req.AccountID)
+		if historyID > 0 {
+			if err := a.historyService.SetArchivePath(historyID, archiveDir); err != nil {
+				log.Printf("[WARN] 记录归档目录失败: %v", err)
+			}
+		}
+	}
+
+	// 任务未能开始时没有删除任何邮件，撤销对计划的占用，允许重新执行
+	job := &model.CleanJob{Kind: model.JobKindPlan, AccountEmail: plan.AccountEmail, HistoryID: historyID, PlanID: planID}
+	started, err := a.runClean(job, &req, archiveDir, plan)
+	if err != nil {
+		a.planService.Release(planID, historyID)
+		return nil, err
+	}
+	return started, nil
+}
+
+// SetPlanExclusions 设置执行计划时排除的邮件（按文件夹的 UID）、发件人和域名
+func (a *App) SetPlanExclusions(planID int64, exclusions model.CleanExclusions) (*model.CleanPlan, error) {
+	return a.planService.SetExclusions(planID, &exclusions)
+}
+
+// GetCleanPlan 获取清理计划
+func (a *App) GetCleanPlan(planID int64) (*model.CleanPlan, error) {
+	return a.planService.Get(planID)
+}
+
+// createPlan 根据预览结果生成清理计划，计划 ID 写入清理结果
+func (a *App) createPlan(req *model.CleanRequest, historyID int64, folders []*model.CleanPlanFolder, result *model.CleanResult) {
+	acc, err := a.accountService.Get(req.AccountID)
+	if err != nil {
+		log.Printf("[WARN] 生成清理计划失败: %v", err)
+		return
+	}
+	plan, err := a.planService.CreateFromPreview(req, acc.Email, historyID, folders)
+	if err != nil {
+		log.Printf("[WARN] 生成清理计划失败: %v", err)
+		return
+	}
+	result.PlanID = plan.ID
+}
+
+// CancelClean 取消指定账号手动开始的清理任务（不含定时清理），取消单个任务请使用 CancelJob
+func (a *App) CancelClean(accountID int64) {
+	a.jobManager.CancelWhere(func(job *model.CleanJob) bool {
+		return job.AccountID == accountID && job.Kind != model.JobKindSchedule
+	})
+}
+
+// ListJobs 获取正在执行和最近结束的清理任务（含定时清理），按开始时间倒序
+func (a *App) ListJobs() []*model.CleanJob {
+	return a.jobManager.List()
+}
+
+// GetJob 获取清理任务
+func (a *App) GetJob(id int64) (*model.CleanJob, error) {
+	return a.jobManager.Get(id)
+}
+
+// CancelJob 取消清理任务
+func (a *App) CancelJob(id int64) error {
+	return a.jobManager.Cancel(id)
+}
+
+// PauseJob 暂停清理任务：当前批次结束后归还连接，历史记录标记为 paused
+func (a *App) PauseJob(id int64) (*model.CleanJob, error) {
+	job, err := a.jobManager.Pause(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.HistoryID > 0 {
+		if err := a.historyService.SetActiveHistoryStatus(job.HistoryID, "paused"); err != nil {
+			log.Printf("[WARN] 更新历史记录状态失败: %v", err)
+		}
+	}
+	return job, nil
+}
+
+// ResumeJob 继续暂停的清理任务，从各文件夹暂停时的位置接着删除
+// 应用退出后中断的任务请使用 ResumeClean
+func (a *App) ResumeJob(id int64) (*model.CleanJob, error) {
+	job, err := a.jobManager.Resume(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.HistoryID > 0 {
+		if err := a.historyService.SetActiveHistoryStatus(job.HistoryID, "running"); err != nil {
+			log.Printf("[WARN] 更新历史记录状态失败: %v", err)
+		}
+	}
+	return job, nil
+}
+
+// ListInterruptedCleans 获取因应用退出而中断的清理任务
+func (a *App) ListInterruptedCleans() ([]model.InterruptedClean, error) {
+	return a.historyService.ListInterrupted()
+}
+
+// ResumeClean 继续中断的清理任务
+// 已完成的文件夹会被跳过；UIDVALIDITY 变化的文件夹会重新搜索，不会删除错误的邮件
+func (a *App) ResumeClean(historyID int64) (*model.CleanJob, error) {
+	history, err := a.historyService.GetHistoryDetail(historyID)
+	if err != nil {
+		return nil, fmt.Errorf("获取历史记录失败: %w", err)
+	}
+	if history.Status != "interrupted" {
+		return nil, fmt.Errorf("该清理任务未中断，无法继续")
+	}
+
+	req, err := a.historyService.GetHistoryRequest(historyID)
+	if err != nil {
+		return nil, fmt.Errorf("读取清理请求失败: %w", err)
+	}
+	if req == nil {
+		return nil, fmt.Errorf("该记录没有保存清理请求，无法继续，请结束该任务")
+	}
+	// 执行清理计划中断的任务继续时仍只删除计划中的邮件，计划已被清理时不能继续
+	plan, err := a.planService.GetExecuted(historyID)
+	if err != nil {
+		return nil, fmt.Errorf("获取清理计划失败: %w", err)
+	}
+	if plan == nil && req.PlanOnly {
+		return nil, fmt.Errorf("该任务执行的清理计划已不存在，无法继续，请结束该任务")
+	}
+	if plan != nil && time.Now().After(plan.ExpiresAt) {
+		return nil, fmt.Errorf("该任务执行的清理计划已过期，无法继续，请结束该任务")
+	}
+	if err := a.jobManager.CheckAvailable(req.AccountID, !req.PreviewOnly); err != nil {
+		return nil, err
+	}
+
+	if err := a.historyService.SetHistoryStatus(historyID, "running"); err != nil {
+		return nil, err
+	}
+	job := &model.CleanJob{Kind: model.JobKindResume, AccountEmail: history.AccountEmail, HistoryID: historyID}
+	if plan != nil {
+		job.PlanID = plan.ID
+	}
+	return a.runClean(job, req, history.ArchivePath, plan)
+}
+
+// FinalizeInterruptedClean 结束中断的清理任务（不再继续），按已完成的进度更新历史记录
+func (a *App) FinalizeInterruptedClean(historyID int64) error {
+	return a.historyService.FinalizeInterrupted(historyID)
+}
+
+// ==================== 归档恢复 ====================
+
+// RestoreFromArchive 将清理前备份的邮件恢复到服务器
+// targetFolder 为空时恢复到邮件原来所在的文件夹
+func (a *App) RestoreFromArchive(historyID int64, targetFolder string) error {
+	history, err := a.historyService.GetHistoryDetail(historyID)
+	if err != nil {
+		return fmt.Errorf("获取历史记录失败: %w", err)
+	}
+	if history.ArchivePath == "" {
+		return fmt.Errorf("该清理记录没有本地备份")
+	}
+
+	cfg, err := a.accountService.GetConnectConfig(history.AccountID)
+	if err != nil {
+		return err
+	}
+
+	pool := a.poolManager.GetPool(history.AccountID, cfg, nil)
+	currentRestorer := restorer.NewRestorer(pool)
+	if err := a.trackRestorer(history.AccountID, currentRestorer); err != nil {
+		return err
+	}
 
 	// 启动进度监听
 	go func() {
-		for progress := range currentCleaner.ProgressChan() {
-			wailsRuntime.EventsEmit(a.ctx, "clean:progress", progress)
+		for progress := range currentRestorer.ProgressChan() {
+			wailsRuntime.EventsEmit(a.ctx, "restore:progress", progress)
 		}
 	}()
 
-	// 异步执行清理（使用局部变量避免竞态）
-	go func(hID int64, c *cleaner.Cleaner) {
-		result, err := c.Clean(&req)
+	// 异步执行恢复
+	go func(r *restorer.Restorer) {
+		defer a.untrackRestorer(history.AccountID)
+		result, err := r.Restore(historyID, history.ArchivePath, targetFolder)
 		if err != nil {
-			// 更新历史记录为失败
-			if hID > 0 {
-				a.historyService.UpdateHistory(hID, 0, 0, "failed", err.Error(), 0)
-			}
-			wailsRuntime.EventsEmit(a.ctx, "clean:error", err.Error())
+			wailsRuntime.EventsEmit(a.ctx, "restore:error", err.Error())
 			return
 		}
-		// 更新历史记录为完成
-		if hID > 0 {
-			matchedCount := 0
-			for _, stat := range result.FolderStats {
-				matchedCount += stat.MatchedCount
-			}
-			a.historyService.UpdateHistory(hID, matchedCount, result.TotalDeleted, result.Status, "", result.Duration)
+		wailsRuntime.EventsEmit(a.ctx, "restore:complete", result)
+	}(currentRestorer)
+
+	return nil
+}
+
+// CancelRestore 取消指定账号正在执行的恢复
+func (a *App) CancelRestore(accountID int64) {
+	a.runnersMu.Lock()
+	r := a.restorers[accountID]
+	a.runnersMu.Unlock()
+	if r != nil {
+		r.Cancel()
+	}
+}
+
+// trackRestorer 登记账号正在执行的恢复，同一账号同时只能有一个
+func (a *App) trackRestorer(accountID int64, r *restorer.Restorer) error {
+	a.runnersMu.Lock()
+	defer a.runnersMu.Unlock()
+	if a.restorers[accountID] != nil {
+		return fmt.Errorf("该账号的恢复任务正在进行中")
+	}
+	a.restorers[accountID] = r
+	return nil
+}
+
+// untrackRestorer 恢复结束后移除登记
+func (a *App) untrackRestorer(accountID int64) {
+	a.runnersMu.Lock()
+	delete(a.restorers, accountID)
+	a.runnersMu.Unlock()
+}
+
+// ==================== 邮箱分析 ====================
+
+// StartAnalysis 开始分析：按发件人、域名、月份和文件夹统计邮件数量和占用空间，结束后保存报告
+func (a *App) StartAnalysis(req model.AnalysisRequest) error {
+	acc, err := a.accountService.Get(req.AccountID)
+	if err != nil {
+		return err
+	}
+	cfg, err := a.accountService.GetConnectConfig(req.AccountID)
+	if err != nil {
+		return err
+	}
+
+	pool := a.poolManager.GetPool(req.AccountID, cfg, nil)
+	currentAnalyzer := analyzer.NewAnalyzer(pool)
+	if err := a.trackAnalyzer(req.AccountID, currentAnalyzer); err != nil {
+		return err
+	}
+
+	// 启动进度监听
+	go func() {
+		for progress := range currentAnalyzer.ProgressChan() {
+			wailsRuntime.EventsEmit(a.ctx, "analysis:progress", progress)
 		}
-		wailsRuntime.EventsEmit(a.ctx, "clean:complete", result)
-	}(historyID, currentCleaner)
+	}()
+
+	// 异步执行分析，取消或部分文件夹失败时也保存已统计的部分
+	go func(an *analyzer.Analyzer) {
+		defer a.untrackAnalyzer(req.AccountID)
+		report, err := an.Analyze(&req)
+		if err != nil {
+			wailsRuntime.EventsEmit(a.ctx, "analysis:error", err.Error())
+			return
+		}
+		if _, err := a.analysisService.Save(report, acc.Email); err != nil {
+			log.Printf("[WARN] %v", err)
+		}
+		wailsRuntime.EventsEmit(a.ctx, "analysis:complete", report)
+	}(currentAnalyzer)
+
+	return nil
+}
 
+// CancelAnalysis 取消指定账号正在执行的分析或最大邮件查询
+func (a *App) CancelAnalysis(accountID int64) {
+	a.runnersMu.Lock()
+	an := a.analyzers[accountID]
+	a.runnersMu.Unlock()
+	if an != nil {
+		an.Cancel()
+	}
+}
+
+// trackAnalyzer 登记账号正在执行的分析，分析和最大邮件查询共用，同一账号同时只能有一个
+func (a *App) trackAnalyzer(accountID int64, an *analyzer.Analyzer) error {
+	a.runnersMu.Lock()
+	defer a.runnersMu.Unlock()
+	if a.analyzers[accountID] != nil {
+		return fmt.Errorf("该账号的分析正在进行中")
+	}
+	a.analyzers[accountID] = an
 	return nil
 }
 
-// CancelClean 取消清理
-func (a *App) CancelClean() {
-	if a.currentCleaner != nil {
-		a.currentCleaner.Cancel()
+// untrackAnalyzer 分析结束后移除登记
+func (a *App) untrackAnalyzer(accountID int64) {
+	a.runnersMu.Lock()
+	delete(a.analyzers, accountID)
+	a.runnersMu.Unlock()
+}
+
+// ListAnalysisReports 获取分析报告列表，accountID 为 0 时返回所有账号的报告
+func (a *App) ListAnalysisReports(accountID int64) ([]*model.AnalysisListItem, error) {
+	return a.analysisService.List(accountID)
+}
+
+// GetAnalysisReport 获取分析报告
+func (a *App) GetAnalysisReport(id int64) (*model.AnalysisReport, error) {
+	return a.analysisService.Get(id)
+}
+
+// DeleteAnalysisReport 删除分析报告
+func (a *App) DeleteAnalysisReport(id int64) error {
+	return a.analysisService.Delete(id)
+}
+
+// AnalysisRowToCleanRequest 将报告中的一行转换为预览模式的清理请求
+// dimension 为 sender、domain、month 或 folder，文件夹和日期范围沿用报告的设置
+func (a *App) AnalysisRowToCleanRequest(reportID int64, dimension model.AnalysisDimension, key string) (*model.CleanRequest, error) {
+	return a.analysisService.CleanRequestFor(reportID, dimension, key)
+}
+
+// StartLargestMessages 查询账号中占用空间最大的邮件，完整完成时根据结果生成清理计划（可通过 ExecutePlan 删除）
+// folders 为空时查询所有文件夹，可通过 CancelAnalysis 取消
+func (a *App) StartLargestMessages(req model.LargestMessagesRequest) error {
+	acc, err := a.accountService.Get(req.AccountID)
+	if err != nil {
+		return err
 	}
+	cfg, err := a.accountService.GetConnectConfig(req.AccountID)
+	if err != nil {
+		return err
+	}
+
+	pool := a.poolManager.GetPool(req.AccountID, cfg, nil)
+	currentAnalyzer := analyzer.NewAnalyzer(pool)
+	if err := a.trackAnalyzer(req.AccountID, currentAnalyzer); err != nil {
+		return err
+	}
+
+	// 启动进度监听
+	go func() {
+		for progress := range currentAnalyzer.ProgressChan() {
+			wailsRuntime.EventsEmit(a.ctx, "largest:progress", progress)
+		}
+	}()
+
+	go func(an *analyzer.Analyzer) {
+		defer a.untrackAnalyzer(req.AccountID)
+		result, err := an.LargestMessages(&req)
+		if err != nil {
+			wailsRuntime.EventsEmit(a.ctx, "largest:error", err.Error())
+			return
+		}
+		if folders := an.PlanFolders(); result.Status == "completed" && len(folders) > 0 {
+			plan, err := a.planService.CreateFromPreview(req.PlanRequest(folders, time.Now()), acc.Email, 0, folders)
+			if err != nil {
+				log.Printf("[WARN] 生成清理计划失败: %v", err)
+			} else {
+				result.PlanID = plan.ID
+			}
+		}
+		wailsRuntime.EventsEmit(a.ctx, "largest:complete", result)
+	}(currentAnalyzer)
+
+	return nil
+}
+
+// ==================== 清理预设 ====================
+
+// ListCleanPresets 获取清理预设列表
+func (a *App) ListCleanPresets() ([]*model.CleanPreset, error) {
+	return a.presetService.List()
+}
+
+// CreateCleanPreset 创建清理预设
+func (a *App) CreateCleanPreset(preset model.CleanPreset) (*model.CleanPreset, error) {
+	preset.ID = 0
+	return a.presetService.Create(&preset)
+}
+
+// UpdateCleanPreset 更新清理预设
+func (a *App) UpdateCleanPreset(preset model.CleanPreset) (*model.CleanPreset, error) {
+	return a.presetService.Update(&preset)
+}
+
+// DeleteCleanPreset 删除清理预设
+func (a *App) DeleteCleanPreset(id int64) error {
+	return a.presetService.Delete(id)
+}
+
+// ApplyCleanPreset 将预设应用到账号，返回预填好的清理请求（日期需另行设置）
+func (a *App) ApplyCleanPreset(presetID, accountID int64) (*model.CleanRequest, error) {
+	return a.presetService.Apply(presetID, accountID)
+}
+
+// CreateCleanPresetFromHistory 将清理历史保存为预设
+func (a *App) CreateCleanPresetFromHistory(historyID int64, name string) (*model.CleanPreset, error) {
+	return a.presetService.CreateFromHistory(historyID, name)
+}
+
+// ==================== 定时清理 ====================
+
+// ListCleanSchedules 获取定时清理计划列表
+func (a *App) ListCleanSchedules() ([]*model.CleanSchedule, error) {
+	return db.ListSchedules()
+}
+
+// SaveCleanSchedule 创建或更新定时清理计划（ID 为 0 时创建）
+func (a *App) SaveCleanSchedule(schedule model.CleanSchedule) (*model.CleanSchedule, error) {
+	return a.scheduler.Save(&schedule)
+}
+
+// SetCleanScheduleEnabled 启用或停用定时清理计划
+func (a *App) SetCleanScheduleEnabled(id int64, enabled bool) error {
+	return a.scheduler.SetEnabled(id, enabled)
+}
+
+// RunCleanScheduleNow 立即执行一次定时清理计划
+func (a *App) RunCleanScheduleNow(id int64) error {
+	return a.scheduler.RunNow(id)
+}
+
+// DeleteCleanSchedule 删除定时清理计划
+func (a *App) DeleteCleanSchedule(id int64) error {
+	return a.scheduler.Delete(id)
 }
 
 // ==================== OAuth2 ====================
@@ -599,6 +1173,100 @@ func (a *App) TestProxy(settings *model.ProxySettings) error {
 	return nil
 }
 
+// ==================== 时区设置 ====================
+
+// GetTimezone 获取解析相对日期使用的时区，为空表示使用系统时区
+func (a *App) GetTimezone() (string, error) {
+	return db.GetTimezone()
+}
+
+// SaveTimezone 保存解析相对日期使用的时区（IANA 时区名，如 Asia/Shanghai），为空表示使用系统时区
+func (a *App) SaveTimezone(timezone string) error {
+	timezone = strings.TrimSpace(timezone)
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("无法识别的时区: %s", timezone)
+		}
+	}
+	if err := db.SaveTimezone(timezone); err != nil {
+		return err
+	}
+	log.Printf("[INFO] 时区已设置为: %s", timezone)
+	return nil
+}
+
+// ==================== 限速设置 ====================
+
+// GetRateLimits 获取各邮箱厂商的 IMAP 命令限速（同一服务器主机的所有连接共享）
+func (a *App) GetRateLimits() ([]model.VendorRateLimit, error) {
+	overrides, err := db.GetRateLimits()
+	if err != nil {
+		return nil, err
+	}
+	var list []model.VendorRateLimit
+	for _, vendor := range model.GetVendorList() {
+		item := model.VendorRateLimit{
+			Vendor:  vendor.Vendor,
+			Name:    vendor.Name,
+			Limit:   vendor.Vendor.DefaultRateLimit(),
+			Default: vendor.Vendor.DefaultRateLimit(),
+		}
+		if limit, ok := overrides[vendor.Vendor]; ok {
+			item.Limit = limit
+			item.Custom = true
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+// SaveRateLimit 保存厂商的限速，下次获取连接池时生效（每秒命令数为 0 表示不限速）
+func (a *App) SaveRateLimit(vendor model.EmailVendorType, limit model.RateLimit) error {
+	if err := limit.Validate(); err != nil {
+		return err
+	}
+	overrides, err := db.GetRateLimits()
+	if err != nil {
+		return err
+	}
+	if overrides == nil {
+		overrides = make(map[model.EmailVendorType]model.RateLimit)
+	}
+	overrides[vendor] = limit
+	if err := db.SaveRateLimits(overrides); err != nil {
+		return err
+	}
+	log.Printf("[INFO] %s 限速已设置为: 每秒 %.1f 条命令，突发 %d", vendor, limit.CommandsPerSecond, limit.Burst)
+	return nil
+}
+
+// ResetRateLimit 恢复厂商的默认限速
+func (a *App) ResetRateLimit(vendor model.EmailVendorType) error {
+	overrides, err := db.GetRateLimits()
+	if err != nil {
+		return err
+	}
+	delete(overrides, vendor)
+	return db.SaveRateLimits(overrides)
+}
+
+// ==================== 受保护发件人 ====================
+
+// GetProtectedSenders 获取受保护的发件人（任何清理都不会删除这些发件人的邮件）
+func (a *App) GetProtectedSenders() (*model.ProtectedSenders, error) {
+	return db.GetProtectedSenders()
+}
+
+// SaveProtectedSenders 保存受保护的发件人地址和域名
+func (a *App) SaveProtectedSenders(protected model.ProtectedSenders) (*model.ProtectedSenders, error) {
+	protected.Normalize()
+	if err := db.SaveProtectedSenders(&protected); err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] 受保护发件人已更新: %d 个地址，%d 个域名", len(protected.Addresses), len(protected.Domains))
+	return &protected, nil
+}
+
 // ==================== 历史记录 ====================
 
 // GetCleanHistoryList 获取清理历史列表
@@ -623,3 +1291,20 @@ func (a *App) DeleteCleanHistory(id int64) error {
 func (a *App) ClearAllCleanHistory() error {
 	return a.historyService.ClearAllHistory()
 }
+
+// ==================== 删除审计 ====================
+
+// SearchDeletedMessages 分页查询删除审计记录，可按账号、清理任务、发件人、主题或 Message-ID 筛选
+func (a *App) SearchDeletedMessages(q model.DeletedMessageQuery) (*model.DeletedMessagePage, error) {
+	return a.auditService.Search(q)
+}
+
+// ExportDeletedMessages 将符合条件的删除审计记录导出为 CSV 或 JSON 文件，返回文件路径和记录数
+func (a *App) ExportDeletedMessages(q model.DeletedMessageQuery, format model.AuditExportFormat) (*model.AuditExportResult, error) {
+	result, err := a.auditService.Export(q, format)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] 已导出 %d 条删除审计记录: %s", result.Count, result.Path)
+	return result, nil
+}
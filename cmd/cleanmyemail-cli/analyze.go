@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"CleanMyEmail/internal/account"
+	"CleanMyEmail/internal/db"
+	"CleanMyEmail/internal/email/analyzer"
+	"CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/model"
+	"CleanMyEmail/internal/service"
+)
+
+// runAnalyze 按发件人、域名、月份和文件夹统计邮件数量和占用空间，或查看已保存的报告
+func runAnalyze(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	accountArg := fs.String("account", "", "账号 ID 或邮箱地址（分析时必填）")
+	folders := fs.String("folders", "", "文件夹列表，逗号分隔（分析时必填）")
+	startDate := fs.String("start", "", "开始日期 YYYY-MM-DD")
+	endDate := fs.String("end", "", "结束日期 YYYY-MM-DD")
+	concurrency := fs.Int("concurrency", 0, "最大并发文件夹数（默认 3）")
+	reportID := fs.Int64("report", 0, "查看已保存的分析报告，不重新分析")
+	top := fs.Int("top", 20, "发件人和域名各列出的行数，0 表示全部")
+	jsonOut := fs.Bool("json", false, "以 JSON 格式输出报告")
+	verbose := fs.Bool("v", false, "输出调试日志")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	setupLogging(*verbose)
+	defer db.Close()
+
+	analysisService := service.NewAnalysisService()
+	var report *model.AnalysisReport
+	var err error
+	if *reportID > 0 {
+		if report, err = analysisService.Get(*reportID); err != nil {
+			return err
+		}
+	} else {
+		if *folders == "" {
+			return usageErrorf("请通过 -folders 指定文件夹，或通过 -report 查看已保存的报告")
+		}
+		accountID, err := resolveAccount(*accountArg)
+		if err != nil {
+			return err
+		}
+		req := &model.AnalysisRequest{
+			AccountID:      accountID,
+			Folders:        splitList(*folders),
+			StartDate:      *startDate,
+			EndDate:        *endDate,
+			MaxConcurrency: *concurrency,
+		}
+		if report, err = executeAnalysis(req, analysisService, !*jsonOut); err != nil {
+			return err
+		}
+	}
+
+	if *jsonOut {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else {
+		printAnalysisReport(report, *top)
+	}
+	if report.Status == "cancelled" {
+		return &exitCodeError{code: exitInterrupted, err: fmt.Errorf("分析已取消")}
+	}
+	if report.Status == "failed" {
+		return &exitCodeError{code: exitPartial, err: fmt.Errorf("部分文件夹分析失败: %s", report.Error)}
+	}
+	return nil
+}
+
+// executeAnalysis 同步执行分析并保存报告
+func executeAnalysis(req *model.AnalysisRequest, analysisService *service.AnalysisService, showProgress bool) (*model.AnalysisReport, error) {
+	accountService := account.NewService()
+	acc, err := accountService.Get(req.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("获取账号失败: %w", err)
+	}
+	cfg, err := accountService.GetConnectConfig(req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := imap.NewConnectionPool(cfg, &imap.PoolOptions{MaxSize: req.MaxConcurrency})
+	pool.SetLimiter(imap.NewRateLimiter(cfg.Server, cfg.RateLimit))
+	defer pool.Close()
+	an := analyzer.NewAnalyzer(pool)
+
+	// Ctrl+C 取消分析，已统计的部分仍会保存
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		an.Cancel()
+	}()
+
+	progressDone := make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		for progress := range an.ProgressChan() {
+			if showProgress && progress.Message != "" {
+				fmt.Fprintln(os.Stderr, progress.Message)
+			}
+		}
+	}()
+
+	report, err := an.Analyze(req)
+	<-progressDone
+	if err != nil {
+		return nil, err
+	}
+	if _, err := analysisService.Save(report, acc.Email); err != nil {
+		fmt.Fprintf(os.Stderr, "警告: %v\n", err)
+	}
+	return report, nil
+}
+
+// printAnalysisReport 以表格形式输出分析报告
+func printAnalysisReport(report *model.AnalysisReport, top int) {
+	printAnalysisRows("发件人", report.BySender, top)
+	printAnalysisRows("域名", report.ByDomain, top)
+	printAnalysisRows("月份", report.ByMonth, 0)
+	printAnalysisRows("文件夹", report.ByFolder, 0)
+
+	fmt.Printf("分析%s: 共 %d 封邮件，%s，耗时 %.1fs\n", statusText(report.Status), report.TotalCount,
+		formatSize(report.TotalSize), report.Duration)
+	if report.ID > 0 {
+		fmt.Printf("分析报告: %d（可通过 analyze -report %d 再次查看）\n", report.ID, report.ID)
+	}
+}
+
+// printAnalysisRows 输出一个维度的统计，top 大于 0 时只输出前 top 行
+func printAnalysisRows(title string, rows []model.AnalysisRow, top int) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\t邮件数\t大小\n", title)
+	for i, row := range rows {
+		if top > 0 && i >= top {
+			break
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", row.Key, row.Count, formatSize(row.Size))
+	}
+	w.Flush()
+	if top > 0 && len(rows) > top {
+		fmt.Printf("（仅列出前 %d 行，共 %d 行，可通过 -top 调整）\n", top, len(rows))
+	}
+	fmt.Println()
+}
+
+// runLargest 列出账号中占用空间最大的邮件，并生成可通过 execute 执行的清理计划
+func runLargest(args []string) error {
+	fs := flag.NewFlagSet("largest", flag.ContinueOnError)
+	accountArg := fs.String("account", "", "账号 ID 或邮箱地址（必填）")
+	folders := fs.String("folders", "", "文件夹列表，逗号分隔（默认所有文件夹）")
+	limit := fs.Int("limit", 50, "列出的邮件数量（最多 1000）")
+	concurrency := fs.Int("concurrency", 0, "最大并发文件夹数（默认 3）")
+	deleteMode := fs.String("delete-mode", "", "执行计划时的删除方式: permanent, trash, folder（默认 permanent）")
+	targetFolder := fs.String("target-folder", "", "delete-mode=folder 时的目标文件夹")
+	backup := fs.Bool("backup", false, "执行计划时先将邮件原文归档到本地")
+	jsonOut := fs.Bool("json", false, "以 JSON 格式输出结果")
+	verbose := fs.Bool("v", false, "输出调试日志")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	setupLogging(*verbose)
+	defer db.Close()
+
+	switch model.DeleteMode(*deleteMode) {
+	case "", model.DeleteModePermanent, model.DeleteModeTrash, model.DeleteModeFolder:
+	default:
+		return usageErrorf("不支持的删除方式: %s", *deleteMode)
+	}
+	accountID, err := resolveAccount(*accountArg)
+	if err != nil {
+		return err
+	}
+	req := &model.LargestMessagesRequest{
+		AccountID:          accountID,
+		Folders:            splitList(*folders),
+		Limit:              *limit,
+		MaxConcurrency:     *concurrency,
+		DeleteMode:         model.DeleteMode(*deleteMode),
+		TargetFolder:       *targetFolder,
+		BackupBeforeDelete: *backup,
+	}
+
+	accountService := account.NewService()
+	acc, err := accountService.Get(accountID)
+	if err != nil {
+		return fmt.Errorf("获取账号失败: %w", err)
+	}
+	cfg, err := accountService.GetConnectConfig(accountID)
+	if err != nil {
+		return err
+	}
+	pool := imap.NewConnectionPool(cfg, &imap.PoolOptions{MaxSize: req.MaxConcurrency})
+	pool.SetLimiter(imap.NewRateLimiter(cfg.Server, cfg.RateLimit))
+	defer pool.Close()
+	an := analyzer.NewAnalyzer(pool)
+
+	// Ctrl+C 取消查询
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		an.Cancel()
+	}()
+
+	progressDone := make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		for progress := range an.ProgressChan() {
+			if !*jsonOut && progress.Message != "" {
+				fmt.Fprintln(os.Stderr, progress.Message)
+			}
+		}
+	}()
+
+	result, err := an.LargestMessages(req)
+	<-progressDone
+	if err != nil {
+		return err
+	}
+
+	if planFolders := an.PlanFolders(); result.Status == "completed" && len(planFolders) > 0 {
+		plan, err := service.NewPlanService().CreateFromPreview(req.PlanRequest(planFolders, time.Now()), acc.Email, 0, planFolders)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "警告: %v\n", err)
+		} else {
+			result.PlanID = plan.ID
+		}
+	}
+
+	if *jsonOut {
+		if err := printJSON(result); err != nil {
+			return err
+		}
+	} else {
+		printPreviewMessages(&model.PreviewPage{Total: len(result.Messages), Messages: result.Messages})
+		fmt.Printf("查询%s: 共扫描 %d 封邮件，最大的 %d 封共 %s，耗时 %.1fs\n", statusText(result.Status),
+			result.ScannedCount, len(result.Messages), formatSize(result.TotalSize), result.Duration)
+		if result.PlanID > 0 {
+			fmt.Printf("清理计划: %d（24 小时内可通过 execute -plan %d 删除以上邮件）\n", result.PlanID, result.PlanID)
+		}
+	}
+	switch result.Status {
+	case "cancelled":
+		return &exitCodeError{code: exitInterrupted, err: fmt.Errorf("查询已取消")}
+	case "failed":
+		return &exitCodeError{code: exitPartial, err: fmt.Errorf("部分文件夹查询失败: %s", result.Error)}
+	}
+	return nil
+}
@@ -0,0 +1,829 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"CleanMyEmail/internal/account"
+	"CleanMyEmail/internal/archive"
+	"CleanMyEmail/internal/db"
+	"CleanMyEmail/internal/email/cleaner"
+	"CleanMyEmail/internal/email/folder"
+	"CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/email/query"
+	"CleanMyEmail/internal/model"
+	"CleanMyEmail/internal/service"
+)
+
+// runAccounts 列出所有账号
+func runAccounts(args []string) error {
+	fs := flag.NewFlagSet("accounts", flag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "以 JSON 格式输出")
+	verbose := fs.Bool("v", false, "输出调试日志")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	setupLogging(*verbose)
+	defer db.Close()
+
+	accounts, err := account.NewService().List()
+	if err != nil {
+		return fmt.Errorf("获取账号列表失败: %w", err)
+	}
+
+	if *jsonOut {
+		if accounts == nil {
+			accounts = []*model.AccountListItem{}
+		}
+		return printJSON(accounts)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\t邮箱\t厂商\t认证方式\t状态")
+	for _, acc := range accounts {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", acc.ID, acc.Email, acc.Vendor, acc.AuthType, acc.Status)
+	}
+	return w.Flush()
+}
+
+// runFolders 打印文件夹树
+func runFolders(args []string) error {
+	fs := flag.NewFlagSet("folders", flag.ContinueOnError)
+	accountArg := fs.String("account", "", "账号 ID 或邮箱地址（必填）")
+	sizes := fs.Bool("sizes", false, "服务器不支持 STATUS=SIZE 时逐封累加计算文件夹占用空间（较慢）")
+	jsonOut := fs.Bool("json", false, "以 JSON 格式输出")
+	verbose := fs.Bool("v", false, "输出调试日志")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	setupLogging(*verbose)
+	defer db.Close()
+
+	accountID, err := resolveAccount(*accountArg)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := account.NewService().GetConnectConfig(accountID)
+	if err != nil {
+		return err
+	}
+	client, err := imap.Connect(cfg)
+	if err != nil {
+		return fmt.Errorf("连接邮箱失败: %w", err)
+	}
+	defer client.Close()
+
+	folders, err := imap.ListMailboxes(client)
+	if err != nil {
+		return err
+	}
+
+	// 不支持 LIST-STATUS 时同步获取邮件数量，指定 -sizes 且不支持 STATUS=SIZE 时再计算占用空间
+	byPath := make(map[string]*model.MailFolder, len(folders))
+	for _, f := range folders {
+		byPath[f.FullPath] = f
+	}
+	applyUpdate := func(update imap.FolderStatusUpdate) {
+		if f := byPath[update.FolderPath]; f != nil {
+			f.MessageCount = update.MessageCount
+			if update.Size >= 0 {
+				f.Size = update.Size
+			}
+		}
+	}
+	if !imap.SupportsListStatus(client) {
+		imap.FetchFolderStatus(client, folders, applyUpdate)
+	}
+	if *sizes && !imap.SupportsStatusSize(client) {
+		imap.FetchFolderSizes(client, folders, applyUpdate)
+	}
+
+	db.UpdateAccountLastConnected(accountID)
+	tree := folder.BuildFolderTree(folders)
+
+	if *jsonOut {
+		return printJSON(tree)
+	}
+	printFolderTree(tree, 0)
+	return nil
+}
+
+// printFolderTree 以缩进形式打印文件夹树
+func printFolderTree(nodes []*model.FolderTreeNode, depth int) {
+	for _, node := range nodes {
+		count := strconv.FormatUint(uint64(node.MessageCount), 10)
+		if node.Size > 0 {
+			count += ", " + formatSize(node.Size)
+		}
+		fmt.Printf("%s%s (%s)\t%s\n", strings.Repeat("  ", depth), node.Label, count, node.FullPath)
+		printFolderTree(node.Children, depth+1)
+	}
+}
+
+// runPreview 预览符合条件的邮件
+func runPreview(args []string) error {
+	return runCleanCommand("preview", args, true)
+}
+
+// runClean 清理邮件
+func runClean(args []string) error {
+	return runCleanCommand("clean", args, false)
+}
+
+// cleanOutput preview/clean 的 JSON 输出
+type cleanOutput struct {
+	HistoryID int64 `json:"historyId"`
+	*model.CleanResult
+	Messages []*model.PreviewMessage `json:"messages,omitempty"` // preview -list 时的邮件明细
+}
+
+// runCleanCommand 执行预览或清理，参数与 model.CleanRequest 对应
+func runCleanCommand(name string, args []string, previewOnly bool) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	accountArg := fs.String("account", "", "账号 ID 或邮箱地址（必填）")
+	folders := fs.String("folders", "", "文件夹列表，逗号分隔（必填）")
+	startDate := fs.String("start", "", "开始日期 YYYY-MM-DD")
+	endDate := fs.String("end", "", "结束日期 YYYY-MM-DD（未指定 -older-than、-between 或 -query 时必填）")
+	olderThan := fs.String("older-than", "", "相对日期：只清理该时间之前的邮件，如 90d、8w、6m、1y（覆盖 -start/-end）")
+	between := fs.String("between", "", "相对日期：清理两个时间之间的邮件，如 2y..1y（覆盖 -start/-end）")
+	dateBasis := fs.String("date-basis", "", "日期依据: internal（服务器收到的时间，默认）, sent（邮件 Date: 头，适合迁移过的邮箱）")
+	sender := fs.String("sender", "", "发件人筛选，多个用逗号分隔")
+	subject := fs.String("subject", "", "主题关键词筛选")
+	size := fs.String("size", "", "大小筛选，如 >1M、<100K")
+	read := fs.String("read", "", "已读状态筛选: seen, unseen, all")
+	filterQuery := fs.String("query", "", `筛选表达式，如 'from:(a.com OR b.com) AND NOT subject:"invoice" AND older:180d'`)
+	batchSize := fs.Int("batch-size", 0, "每批处理的邮件数量（默认 500；与 -adaptive-batch 同时使用时为上限）")
+	adaptiveBatch := fs.Bool("adaptive-batch", false, "自适应批大小：从小批开始，按服务器响应耗时和错误自动调整")
+	concurrency := fs.Int("concurrency", 0, "最大并发文件夹数（默认 3）")
+	clientFallback := fs.Bool("client-fallback", false, "服务端不支持发件人/主题搜索时回退到客户端过滤")
+	deleteMode := fs.String("delete-mode", "", "删除方式: permanent, trash, folder（默认 permanent）")
+	targetFolder := fs.String("target-folder", "", "delete-mode=folder 时的目标文件夹")
+	backup := fs.Bool("backup", false, "删除前将邮件原文归档到本地")
+	excludeSenders := fs.String("exclude-senders", "", "排除的发件人地址，多个用逗号分隔")
+	excludeDomains := fs.String("exclude-domains", "", "排除的发件人域名（含子域名），多个用逗号分隔")
+	protectFlagged := fs.Bool("protect-flagged", false, "不删除已加星标（\\Flagged）的邮件")
+	protectAnswered := fs.Bool("protect-answered", false, "不删除已回复（\\Answered）的邮件")
+	protectDraft := fs.Bool("protect-draft", false, "不删除草稿（\\Draft）")
+	protectKeywords := fs.String("protect-keywords", "", "不删除带有这些关键字的邮件，多个用逗号分隔，如 $Important")
+	stripAttachments := fs.Bool("strip-attachments", false, "只移除附件：附件保存到本地，邮件去掉附件后重新上传，再删除原邮件")
+	minAttachmentSize := fs.String("min-attachment-size", "", "只移除不小于该大小的附件，如 500K、2M（配合 -strip-attachments）")
+	var list, sortDesc bool
+	var sortBy string
+	var limit int
+	if previewOnly {
+		fs.BoolVar(&list, "list", false, "列出符合条件的邮件明细")
+		fs.StringVar(&sortBy, "sort", "date", "邮件明细排序: date, size, from, subject, folder")
+		fs.BoolVar(&sortDesc, "desc", false, "邮件明细倒序排列")
+		fs.IntVar(&limit, "limit", 50, "最多列出的邮件数量，0 表示全部")
+	}
+	jsonOut := fs.Bool("json", false, "以 JSON 格式输出结果")
+	verbose := fs.Bool("v", false, "输出调试日志")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	setupLogging(*verbose)
+	defer db.Close()
+
+	if *folders == "" {
+		return usageErrorf("请通过 -folders 指定文件夹")
+	}
+	if *endDate == "" && *olderThan == "" && *between == "" && *filterQuery == "" {
+		return usageErrorf("请通过 -end、-older-than 或 -between 指定日期，或通过 -query 指定筛选表达式")
+	}
+	if _, err := query.Parse(*filterQuery, db.GetLocation()); err != nil {
+		return usageErrorf("筛选表达式错误: %v", err)
+	}
+	switch model.DeleteMode(*deleteMode) {
+	case "", model.DeleteModePermanent, model.DeleteModeTrash, model.DeleteModeFolder:
+	default:
+		return usageErrorf("不支持的删除方式: %s", *deleteMode)
+	}
+	minAttachmentBytes, err := parseByteSize(*minAttachmentSize)
+	if err != nil {
+		return usageErrorf("附件大小格式错误: %s", *minAttachmentSize)
+	}
+	action := model.CleanActionDelete
+	if *stripAttachments {
+		action = model.CleanActionStripAttachments
+	}
+	switch model.DateBasis(*dateBasis) {
+	case "", model.DateBasisInternal, model.DateBasisSent:
+	default:
+		return usageErrorf("不支持的日期依据: %s", *dateBasis)
+	}
+	switch model.PreviewSortField(sortBy) {
+	case "", model.PreviewSortDate, model.PreviewSortSize, model.PreviewSortFrom, model.PreviewSortSubject, model.PreviewSortFolder:
+	default:
+		return usageErrorf("不支持的排序字段: %s", sortBy)
+	}
+
+	accountID, err := resolveAccount(*accountArg)
+	if err != nil {
+		return err
+	}
+
+	req := &model.CleanRequest{
+		AccountID:            accountID,
+		Folders:              splitList(*folders),
+		StartDate:            *startDate,
+		EndDate:              *endDate,
+		OlderThan:            *olderThan,
+		Between:              *between,
+		DateBasis:            model.DateBasis(*dateBasis),
+		PreviewOnly:          previewOnly,
+		BatchSize:            *batchSize,
+		AdaptiveBatch:        *adaptiveBatch,
+		MaxConcurrency:       *concurrency,
+		FilterSender:         *sender,
+		FilterSubject:        *subject,
+		FilterSize:           *size,
+		FilterRead:           *read,
+		FilterQuery:          *filterQuery,
+		EnableClientFallback: *clientFallback,
+		DeleteMode:           model.DeleteMode(*deleteMode),
+		TargetFolder:         *targetFolder,
+		BackupBeforeDelete:   *backup,
+		Exclusions:           parseExclusions(*excludeSenders, *excludeDomains),
+		ProtectFlagged:       *protectFlagged,
+		ProtectAnswered:      *protectAnswered,
+		ProtectDraft:         *protectDraft,
+		ProtectKeywords:      splitList(*protectKeywords),
+		Action:               action,
+		MinAttachmentSize:    minAttachmentBytes,
+	}
+	// 相对日期按设置的时区解析，表达式和解析后的日期都记录到历史
+	if err := req.ResolveDates(time.Now().In(db.GetLocation())); err != nil {
+		return usageErrorf("%v", err)
+	}
+	if req.HasRelativeDate() && !*jsonOut {
+		fmt.Fprintf(os.Stderr, "%s 解析为 %s ~ %s\n", req.DateExpression(), req.StartDate, req.EndDate)
+	}
+
+	historyID, result, messages, err := executeClean(req, nil, !*jsonOut)
+	if err != nil {
+		return err
+	}
+
+	var page *model.PreviewPage
+	if list {
+		previews := service.NewPreviewService()
+		previews.Save(accountID, historyID, messages)
+		pageSize := limit
+		if pageSize <= 0 {
+			pageSize = max(len(messages), 1)
+		}
+		page, err = previews.Page(model.PreviewQuery{
+			AccountID: accountID,
+			PageSize:  pageSize,
+			SortBy:    model.PreviewSortField(sortBy),
+			SortDesc:  sortDesc,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if *jsonOut {
+		output := cleanOutput{HistoryID: historyID, CleanResult: result}
+		if page != nil {
+			output.Messages = page.Messages
+		}
+		if err := printJSON(output); err != nil {
+			return err
+		}
+	} else {
+		if page != nil {
+			printPreviewMessages(page)
+		}
+		printCleanResult(result, previewOnly)
+	}
+
+	return resultError(result)
+}
+
+// runExecute 执行预览生成的清理计划，只删除预览时确定的邮件
+func runExecute(args []string) error {
+	fs := flag.NewFlagSet("execute", flag.ContinueOnError)
+	planID := fs.Int64("plan", 0, "清理计划 ID（preview 输出，必填）")
+	excludeSenders := fs.String("exclude-senders", "", "排除的发件人地址，多个用逗号分隔")
+	excludeDomains := fs.String("exclude-domains", "", "排除的发件人域名（含子域名），多个用逗号分隔")
+	jsonOut := fs.Bool("json", false, "以 JSON 格式输出结果")
+	verbose := fs.Bool("v", false, "输出调试日志")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	setupLogging(*verbose)
+	defer db.Close()
+
+	if *planID <= 0 {
+		return usageErrorf("请通过 -plan 指定清理计划 ID")
+	}
+	planService := service.NewPlanService()
+	plan, err := planService.Prepare(*planID)
+	if err != nil {
+		return err
+	}
+	if exclusions := parseExclusions(*excludeSenders, *excludeDomains); exclusions != nil {
+		if plan, err = planService.SetExclusions(*planID, exclusions); err != nil {
+			return err
+		}
+	}
+
+	req := plan.ExecuteRequest()
+	historyID, result, _, err := executeClean(&req, plan, !*jsonOut)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		if err := printJSON(cleanOutput{HistoryID: historyID, CleanResult: result}); err != nil {
+			return err
+		}
+	} else {
+		printCleanResult(result, false)
+	}
+	return resultError(result)
+}
+
+// resultError 根据清理结果返回对应退出码的错误
+func resultError(result *model.CleanResult) error {
+	switch {
+	case result.Status == "cancelled":
+		return &exitCodeError{code: exitInterrupted, err: fmt.Errorf("清理已取消")}
+	case hasFailedFolder(result):
+		return &exitCodeError{code: exitPartial, err: fmt.Errorf("部分文件夹处理失败")}
+	}
+	return nil
+}
+
+// executeClean 同步执行清理并记录历史，与桌面应用的 runClean 流程一致
+// plan 不为空时只删除计划中的邮件；预览模式下同时返回符合条件的邮件明细，并生成清理计划
+func executeClean(req *model.CleanRequest, plan *model.CleanPlan, showProgress bool) (int64, *model.CleanResult, []*model.PreviewMessage, error) {
+	accountService := account.NewService()
+	historyService := service.NewHistoryService()
+	planService := service.NewPlanService()
+
+	acc, err := accountService.Get(req.AccountID)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("获取账号失败: %w", err)
+	}
+	cfg, err := accountService.GetConnectConfig(req.AccountID)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	historyID, err := historyService.CreateHistory(req, acc.Email)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "警告: 创建历史记录失败: %v\n", err)
+	}
+	if plan != nil {
+		if err := planService.Claim(plan.ID, historyID); err != nil {
+			if historyID > 0 {
+				historyService.UpdateHistory(historyID, 0, 0, "failed", err.Error(), 0)
+			}
+			return historyID, nil, nil, err
+		}
+	}
+
+	c := cleaner.NewCleanerWithConfig(cfg, req.GetMaxConcurrency())
+	if plan != nil {
+		c.SetPlan(plan)
+	}
+	if req.BackupBeforeDelete && !req.PreviewOnly {
+		archiveDir := archive.NewRunDir(req.AccountID)
+		c.SetArchiveDir(archiveDir)
+		if historyID > 0 {
+			historyService.SetArchivePath(historyID, archiveDir)
+		}
+	}
+	if historyID > 0 && !req.PreviewOnly {
+		c.EnableCheckpoint(historyID, historyService)
+		c.EnableAudit(historyID, acc.Email, service.NewAuditService())
+	}
+
+	// Ctrl+C 取消清理，已完成的批次不受影响
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		c.Cancel()
+	}()
+
+	progressDone := make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		// 进度按间隔合并发布，同一条消息可能出现在多个快照中
+		lastMessage := ""
+		for progress := range c.ProgressChan() {
+			if showProgress && progress.Message != "" && progress.Message != lastMessage {
+				fmt.Fprintln(os.Stderr, progress.Message)
+				lastMessage = progress.Message
+			}
+		}
+	}()
+
+	result, err := c.Clean(req)
+	<-progressDone
+	if err != nil {
+		if historyID > 0 {
+			historyService.UpdateHistory(historyID, 0, 0, "failed", err.Error(), 0)
+			historyService.DeleteCheckpoints(historyID)
+		}
+		return historyID, nil, nil, err
+	}
+
+	if historyID > 0 {
+		matchedCount := 0
+		for _, stat := range result.FolderStats {
+			matchedCount += stat.MatchedCount
+		}
+		historyService.UpdateHistory(historyID, matchedCount, result.TotalDeleted, result.Status, "", result.Duration)
+		historyService.DeleteCheckpoints(historyID)
+	}
+	if req.PreviewOnly && result.Status == "completed" {
+		if created, err := planService.CreateFromPreview(req, acc.Email, historyID, c.PlanFolders()); err != nil {
+			fmt.Fprintf(os.Stderr, "警告: %v\n", err)
+		} else {
+			result.PlanID = created.ID
+		}
+	}
+	return historyID, result, c.PreviewMessages(), nil
+}
+
+// printPreviewMessages 以表格形式输出预览的邮件明细
+func printPreviewMessages(page *model.PreviewPage) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "文件夹\tUID\t日期\t大小\t发件人\t主题")
+	for _, msg := range page.Messages {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", msg.Folder, msg.UID, msg.Date.Local().Format("2006-01-02 15:04"),
+			formatSize(msg.Size), msg.From, msg.Subject)
+	}
+	w.Flush()
+	if len(page.Messages) < page.Total {
+		fmt.Printf("（仅列出前 %d 封，共 %d 封，可通过 -limit 调整）\n", len(page.Messages), page.Total)
+	}
+	fmt.Println()
+}
+
+// formatSize 格式化邮件大小
+func formatSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1fM", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1fK", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
+
+// parseByteSize 解析带 K、M、G 后缀的大小，空字符串表示 0
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	var multiplier int64 = 1
+	switch {
+	case strings.HasSuffix(s, "G"):
+		multiplier, s = 1024*1024*1024, s[:len(s)-1]
+	case strings.HasSuffix(s, "M"):
+		multiplier, s = 1024*1024, s[:len(s)-1]
+	case strings.HasSuffix(s, "K"):
+		multiplier, s = 1024, s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("无效的大小: %s", s)
+	}
+	return n * multiplier, nil
+}
+
+// printCleanResult 以表格形式输出清理结果
+func printCleanResult(result *model.CleanResult, previewOnly bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "文件夹\t匹配\t排除\t受保护\t删除\t释放\t状态\t说明")
+	for _, stat := range result.FolderStats {
+		note := stat.Error
+		if note == "" {
+			note = stat.Warning
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\t%s\t%s\n", stat.Folder, stat.MatchedCount, stat.ExcludedCount,
+			stat.ProtectedCount, stat.DeletedCount, formatSize(stat.ReclaimedBytes), stat.Status, note)
+	}
+	w.Flush()
+
+	if previewOnly {
+		matched := 0
+		for _, stat := range result.FolderStats {
+			matched += stat.MatchedCount
+		}
+		fmt.Printf("\n预览完成: 共 %d 封邮件符合条件，耗时 %.1fs\n", matched, result.Duration)
+		if result.PlanID > 0 && matched > 0 {
+			fmt.Printf("清理计划: %d（24 小时内可通过 execute -plan %d 删除以上邮件）\n", result.PlanID, result.PlanID)
+		}
+		return
+	}
+	if result.AttachmentDir != "" {
+		fmt.Printf("\n清理%s: 共移除 %d 封邮件的附件，耗时 %.1fs\n", statusText(result.Status), result.TotalDeleted, result.Duration)
+	} else {
+		fmt.Printf("\n清理%s: 共删除 %d 封邮件，耗时 %.1fs\n", statusText(result.Status), result.TotalDeleted, result.Duration)
+	}
+	if result.ArchivePath != "" {
+		fmt.Printf("备份目录: %s\n", result.ArchivePath)
+	}
+	if result.AttachmentDir != "" {
+		fmt.Printf("移除附件共释放 %s，附件保存在: %s\n", formatSize(result.ReclaimedBytes), result.AttachmentDir)
+	}
+}
+
+// statusText 状态的中文描述
+func statusText(status string) string {
+	switch status {
+	case "completed":
+		return "完成"
+	case "cancelled":
+		return "已取消"
+	case "failed":
+		return "失败"
+	default:
+		return status
+	}
+}
+
+// hasFailedFolder 是否有文件夹处理失败
+func hasFailedFolder(result *model.CleanResult) bool {
+	for _, stat := range result.FolderStats {
+		if stat.Status == "failed" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFlags 解析命令选项，-h 时返回 errHelp
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return usageErrorf("%v", err)
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("多余的参数: %s", strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
+
+// resolveAccount 根据 ID 或邮箱地址查找账号
+func resolveAccount(arg string) (int64, error) {
+	if arg == "" {
+		return 0, usageErrorf("请通过 -account 指定账号 ID 或邮箱")
+	}
+	if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		if _, err := db.GetAccountByID(id); err != nil {
+			return 0, fmt.Errorf("账号 %d 不存在", id)
+		}
+		return id, nil
+	}
+	acc, err := db.GetAccountByEmail(arg)
+	if err != nil {
+		return 0, fmt.Errorf("账号 %s 不存在", arg)
+	}
+	return acc.ID, nil
+}
+
+// parseExclusions 解析排除的发件人和域名，都为空时返回 nil
+func parseExclusions(senders, domains string) *model.CleanExclusions {
+	exclusions := &model.CleanExclusions{Senders: splitList(senders), Domains: splitList(domains)}
+	exclusions.Normalize()
+	if exclusions.IsEmpty() {
+		return nil
+	}
+	return exclusions
+}
+
+// splitList 解析逗号分隔的列表
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// runProtected 查看或修改受保护的发件人
+func runProtected(args []string) error {
+	fs := flag.NewFlagSet("protected", flag.ContinueOnError)
+	add := fs.String("add", "", "添加受保护的发件人地址或域名，多个用逗号分隔（如 boss@example.com,vip.com）")
+	remove := fs.String("remove", "", "移除受保护的发件人地址或域名，多个用逗号分隔")
+	jsonOut := fs.Bool("json", false, "以 JSON 格式输出")
+	verbose := fs.Bool("v", false, "输出调试日志")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	setupLogging(*verbose)
+	defer db.Close()
+
+	protected, err := db.GetProtectedSenders()
+	if err != nil {
+		return fmt.Errorf("获取受保护发件人失败: %w", err)
+	}
+
+	if *add != "" || *remove != "" {
+		removed := make(map[string]bool)
+		for _, item := range splitList(*remove) {
+			removed[strings.TrimPrefix(strings.ToLower(item), "@")] = true
+		}
+		for _, item := range splitList(*add) {
+			if local, _, found := strings.Cut(item, "@"); found && local != "" {
+				protected.Addresses = append(protected.Addresses, item)
+			} else {
+				protected.Domains = append(protected.Domains, item)
+			}
+		}
+		protected.Normalize()
+		protected.Addresses = removeItems(protected.Addresses, removed)
+		protected.Domains = removeItems(protected.Domains, removed)
+		if err := db.SaveProtectedSenders(protected); err != nil {
+			return fmt.Errorf("保存受保护发件人失败: %w", err)
+		}
+	}
+
+	if *jsonOut {
+		return printJSON(protected)
+	}
+	for _, addr := range protected.Addresses {
+		fmt.Printf("地址\t%s\n", addr)
+	}
+	for _, domain := range protected.Domains {
+		fmt.Printf("域名\t%s\n", domain)
+	}
+	return nil
+}
+
+// runRateLimit 查看或修改各邮箱厂商的 IMAP 命令限速
+func runRateLimit(args []string) error {
+	fs := flag.NewFlagSet("ratelimit", flag.ContinueOnError)
+	vendor := fs.String("vendor", "", "要修改的邮箱厂商（如 gmail、qq、163-personal）")
+	rate := fs.Float64("rate", -1, "每秒允许的命令数，0 表示不限速")
+	burst := fs.Int("burst", 0, "突发容量（默认与每秒命令数相同）")
+	reset := fs.Bool("reset", false, "恢复该厂商的默认限速")
+	jsonOut := fs.Bool("json", false, "以 JSON 格式输出")
+	verbose := fs.Bool("v", false, "输出调试日志")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	setupLogging(*verbose)
+	defer db.Close()
+
+	overrides, err := db.GetRateLimits()
+	if err != nil {
+		return fmt.Errorf("获取限速设置失败: %w", err)
+	}
+
+	if *vendor != "" {
+		v := model.EmailVendorType(*vendor)
+		switch {
+		case *reset:
+			delete(overrides, v)
+		case *rate >= 0:
+			limit := model.RateLimit{CommandsPerSecond: *rate, Burst: *burst}
+			if limit.Burst == 0 {
+				limit.Burst = max(1, int(*rate))
+			}
+			if err := limit.Validate(); err != nil {
+				return usageErrorf("%v", err)
+			}
+			if overrides == nil {
+				overrides = make(map[model.EmailVendorType]model.RateLimit)
+			}
+			overrides[v] = limit
+		default:
+			return usageErrorf("请指定 -rate 或 -reset")
+		}
+		if err := db.SaveRateLimits(overrides); err != nil {
+			return fmt.Errorf("保存限速设置失败: %w", err)
+		}
+	} else if *reset || *rate >= 0 {
+		return usageErrorf("请用 -vendor 指定邮箱厂商")
+	}
+
+	var list []model.VendorRateLimit
+	for _, info := range model.GetVendorList() {
+		item := model.VendorRateLimit{
+			Vendor:  info.Vendor,
+			Name:    info.Name,
+			Limit:   info.Vendor.DefaultRateLimit(),
+			Default: info.Vendor.DefaultRateLimit(),
+		}
+		if limit, ok := overrides[info.Vendor]; ok {
+			item.Limit, item.Custom = limit, true
+		}
+		list = append(list, item)
+	}
+	if *jsonOut {
+		return printJSON(list)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "厂商\t名称\t每秒命令数\t突发\t来源")
+	for _, item := range list {
+		source := "默认"
+		if item.Custom {
+			source = "自定义"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%g\t%d\t%s\n", item.Vendor, item.Name, item.Limit.CommandsPerSecond, item.Limit.Burst, source)
+	}
+	return w.Flush()
+}
+
+// runAudit 查询或导出删除审计记录
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	accountArg := fs.String("account", "", "账号 ID 或邮箱地址（为空时不限账号）")
+	historyID := fs.Int64("history", 0, "只查询某次清理（历史记录 ID）删除的邮件")
+	sender := fs.String("sender", "", "发件人包含的文本")
+	subject := fs.String("subject", "", "主题包含的文本")
+	messageID := fs.String("message-id", "", "Message-ID（尖括号可省略）")
+	limit := fs.Int("limit", 50, "最多列出的记录数")
+	export := fs.String("export", "", "导出全部符合条件的记录：csv 或 json")
+	jsonOut := fs.Bool("json", false, "以 JSON 格式输出")
+	verbose := fs.Bool("v", false, "输出调试日志")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	setupLogging(*verbose)
+	defer db.Close()
+
+	q := model.DeletedMessageQuery{
+		HistoryID: *historyID,
+		Sender:    *sender,
+		Subject:   *subject,
+		MessageID: *messageID,
+		Page:      1,
+		PageSize:  *limit,
+	}
+	if *accountArg != "" {
+		accountID, err := resolveAccount(*accountArg)
+		if err != nil {
+			return err
+		}
+		q.AccountID = accountID
+	}
+
+	auditService := service.NewAuditService()
+	if *export != "" {
+		result, err := auditService.Export(q, model.AuditExportFormat(*export))
+		if err != nil {
+			return err
+		}
+		if *jsonOut {
+			return printJSON(result)
+		}
+		fmt.Printf("已导出 %d 条记录: %s\n", result.Count, result.Path)
+		return nil
+	}
+
+	page, err := auditService.Search(q)
+	if err != nil {
+		return fmt.Errorf("查询删除审计记录失败: %w", err)
+	}
+	if *jsonOut {
+		return printJSON(page)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "删除时间\t任务\t账号\t文件夹\t方式\t状态\t大小\t发件人\t主题\tMessage-ID")
+	for _, m := range page.Messages {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", m.DeletedAt.Local().Format("2006-01-02 15:04"), m.HistoryID,
+			m.AccountEmail, m.Folder, m.Action, m.Status, formatSize(m.Size), m.From, m.Subject, m.MessageID)
+	}
+	w.Flush()
+	if len(page.Messages) < page.Total {
+		fmt.Printf("（仅列出最近 %d 条，共 %d 条，可通过 -limit 调整或 -export 导出全部）\n", len(page.Messages), page.Total)
+	}
+	return nil
+}
+
+// removeItems 去掉 removed 中的项
+func removeItems(items []string, removed map[string]bool) []string {
+	var result []string
+	for _, item := range items {
+		if !removed[item] {
+			result = append(result, item)
+		}
+	}
+	return result
+}
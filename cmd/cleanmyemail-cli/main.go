@@ -0,0 +1,123 @@
+// cleanmyemail-cli 命令行版本，复用桌面应用的账号、清理器和历史记录服务，
+// 使用相同的 SQLite 数据库，便于在服务器上脚本化清理
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// 退出码
+const (
+	exitOK          = 0   // 成功
+	exitError       = 1   // 一般错误（连接失败、账号不存在等）
+	exitUsage       = 2   // 参数错误
+	exitPartial     = 3   // 部分文件夹清理失败
+	exitInterrupted = 130 // 被 Ctrl+C 中断
+)
+
+// exitCodeError 带退出码的错误
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+// errHelp 用户请求查看命令帮助（-h），不视为错误
+var errHelp = errors.New("help requested")
+
+// usageErrorf 构造参数错误
+func usageErrorf(format string, args ...any) error {
+	return &exitCodeError{code: exitUsage, err: fmt.Errorf(format, args...)}
+}
+
+// command 子命令
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"accounts", "列出所有邮箱账号", runAccounts},
+	{"folders", "打印账号的文件夹树", runFolders},
+	{"preview", "预览符合条件的邮件（不删除），并生成清理计划", runPreview},
+	{"clean", "按条件清理邮件", runClean},
+	{"execute", "执行预览生成的清理计划，只删除预览中的邮件", runExecute},
+	{"analyze", "按发件人、域名、月份和文件夹统计邮件数量和占用空间", runAnalyze},
+	{"largest", "列出占用空间最大的邮件，并生成清理计划", runLargest},
+	{"protected", "查看或修改受保护的发件人（任何清理都不会删除）", runProtected},
+	{"ratelimit", "查看或修改各邮箱厂商的 IMAP 命令限速", runRateLimit},
+	{"audit", "按发件人、主题或 Message-ID 查询或导出已删除邮件的审计记录", runAudit},
+	{"schedules", "列出定时清理计划", runSchedules},
+	{"daemon", "守护进程模式，按计划执行定时清理", runDaemon},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run 解析子命令并执行，返回退出码
+func run(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		printUsage(os.Stdout)
+		return exitOK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:])
+		if err == nil || errors.Is(err, errHelp) {
+			return exitOK
+		}
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		var codeErr *exitCodeError
+		if errors.As(err, &codeErr) {
+			return codeErr.code
+		}
+		return exitError
+	}
+
+	fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", args[0])
+	printUsage(os.Stderr)
+	return exitUsage
+}
+
+// printUsage 打印帮助信息
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "用法: cleanmyemail-cli <命令> [选项]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "命令:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "使用 cleanmyemail-cli <命令> -h 查看命令选项")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "退出码: 0 成功, 1 错误, 2 参数错误, 3 部分文件夹失败, 130 被中断")
+}
+
+// setupLogging 默认隐藏内部调试日志，-v 时输出到 stderr
+func setupLogging(verbose bool) {
+	if verbose {
+		log.SetOutput(os.Stderr)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+}
+
+// printJSON 以 JSON 格式输出到 stdout
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"CleanMyEmail/internal/account"
+	"CleanMyEmail/internal/db"
+	"CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/job"
+	"CleanMyEmail/internal/model"
+	"CleanMyEmail/internal/scheduler"
+	"CleanMyEmail/internal/service"
+)
+
+// runSchedules 列出定时清理计划
+func runSchedules(args []string) error {
+	fs := flag.NewFlagSet("schedules", flag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "以 JSON 格式输出")
+	verbose := fs.Bool("v", false, "输出调试日志")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	setupLogging(*verbose)
+	defer db.Close()
+
+	schedules, err := db.ListSchedules()
+	if err != nil {
+		return fmt.Errorf("获取定时清理失败: %w", err)
+	}
+
+	if *jsonOut {
+		if schedules == nil {
+			schedules = []*model.CleanSchedule{}
+		}
+		return printJSON(schedules)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\t名称\t账号\t调度\t启用\t下次执行\t上次状态")
+	for _, s := range schedules {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s %s\t%v\t%s\t%s\n", s.ID, s.Name, s.AccountEmail,
+			s.ScheduleType, s.Spec, s.Enabled, formatTime(s.NextRunAt), s.LastStatus)
+	}
+	return w.Flush()
+}
+
+// runDaemon 守护进程模式：在桌面应用未运行时按计划执行定时清理，直到收到退出信号
+func runDaemon(args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	verbose := fs.Bool("v", false, "输出调试日志")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	setupLogging(*verbose)
+	defer db.Close()
+
+	if _, err := db.GetDB(); err != nil {
+		return fmt.Errorf("初始化数据库失败: %w", err)
+	}
+
+	poolManager := imap.NewPoolManager()
+	defer poolManager.Close()
+
+	s := scheduler.NewScheduler(account.NewService(), service.NewHistoryService(), poolManager, job.NewManager())
+	s.SetEventHandler(func(event string, data *model.ScheduleRunEvent) {
+		switch event {
+		case "schedule:start":
+			fmt.Fprintf(os.Stderr, "[%s] 开始执行 %q（历史 #%d）\n", time.Now().Format(time.DateTime), data.ScheduleName, data.HistoryID)
+		case "schedule:complete":
+			deleted := 0
+			if data.Result != nil {
+				deleted = data.Result.TotalDeleted
+			}
+			fmt.Fprintf(os.Stderr, "[%s] %q 执行%s，删除 %d 封邮件 %s\n", time.Now().Format(time.DateTime),
+				data.ScheduleName, statusText(data.Status), deleted, data.Error)
+		}
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintln(os.Stderr, "定时清理守护进程已启动，按 Ctrl+C 退出")
+	s.Start()
+	<-ctx.Done()
+	fmt.Fprintln(os.Stderr, "正在停止，等待执行中的清理结束...")
+	s.Stop()
+	return nil
+}
+
+// formatTime 格式化可选时间
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -210,6 +210,8 @@ func (s *Service) buildConnectConfig(account *model.EmailAccount) (*imap.Connect
 		Username: account.Email,
 		Password: account.Password,
 		AuthType: account.AuthType,
+		// 限速按厂商默认值和用户设置确定
+		RateLimit: db.GetRateLimit(account.Vendor),
 	}
 
 	// 如果是OAuth2，需要获取并可能刷新access token
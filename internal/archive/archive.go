@@ -0,0 +1,313 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"CleanMyEmail/internal/config"
+)
+
+const (
+	indexFileName = "index.jsonl" // 归档索引文件名
+	mboxExt       = ".mbox"       // 每个文件夹一个 mbox 文件
+)
+
+// Message 待归档的邮件
+type Message struct {
+	UID          uint32
+	Flags        []string
+	InternalDate time.Time
+	MessageID    string
+	Body         []byte // 完整的 RFC 822 原文
+}
+
+// Entry 归档索引条目，记录邮件在 mbox 文件中的位置及原始属性
+type Entry struct {
+	Folder       string    `json:"folder"`
+	File         string    `json:"file"`
+	Offset       int64     `json:"offset"` // 转义后正文在 mbox 文件中的起始位置
+	Length       int64     `json:"length"` // 转义后正文长度
+	UID          uint32    `json:"uid"`
+	Flags        []string  `json:"flags"`
+	InternalDate time.Time `json:"internalDate"`
+	MessageID    string    `json:"messageId"`
+	Size         int64     `json:"size"` // 原文大小
+}
+
+// Writer 归档写入器（一次清理任务对应一个归档目录）
+type Writer struct {
+	dir     string
+	mu      sync.Mutex
+	index   *os.File
+	folders map[string]*folderFile // key: 文件夹名
+	names   map[string]bool        // 已使用的 mbox 文件名
+}
+
+// folderFile 单个文件夹的 mbox 文件
+type folderFile struct {
+	file     *os.File
+	name     string
+	offset   int64
+	archived map[uint32]bool // 已归档的 UID，用于重试时去重
+}
+
+// NewRunDir 生成一次清理任务的归档目录路径
+func NewRunDir(accountID int64) string {
+	name := fmt.Sprintf("%s-account%d", time.Now().Format("20060102-150405"), accountID)
+	return filepath.Join(config.GetDataDir(), "archives", name)
+}
+
+// NewWriter 创建归档写入器
+func NewWriter(dir string) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建归档目录失败: %w", err)
+	}
+	index, err := os.OpenFile(filepath.Join(dir, indexFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("创建归档索引失败: %w", err)
+	}
+	return &Writer{
+		dir:     dir,
+		index:   index,
+		folders: make(map[string]*folderFile),
+		names:   make(map[string]bool),
+	}, nil
+}
+
+// Dir 获取归档目录
+func (w *Writer) Dir() string {
+	return w.dir
+}
+
+// IsArchived 检查邮件是否已归档
+func (w *Writer) IsArchived(folder string, uid uint32) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ff, ok := w.folders[folder]
+	return ok && ff.archived[uid]
+}
+
+// Write 写入一封邮件（调用 Sync 后才保证落盘）
+func (w *Writer) Write(folder string, msg *Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ff, err := w.folderFileLocked(folder)
+	if err != nil {
+		return err
+	}
+	if ff.archived[msg.UID] {
+		return nil
+	}
+
+	date := msg.InternalDate
+	if date.IsZero() {
+		date = time.Now()
+	}
+	fromLine := fmt.Sprintf("From MAILER-DAEMON %s\n", date.UTC().Format(time.ANSIC))
+	escaped := escapeFromLines(msg.Body)
+
+	var buf bytes.Buffer
+	buf.Grow(len(fromLine) + len(escaped) + 2)
+	buf.WriteString(fromLine)
+	buf.Write(escaped)
+	if !bytes.HasSuffix(escaped, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	if _, err := ff.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("写入归档文件失败: %w", err)
+	}
+
+	entry := Entry{
+		Folder:       folder,
+		File:         ff.name,
+		Offset:       ff.offset + int64(len(fromLine)),
+		Length:       int64(len(escaped)),
+		UID:          msg.UID,
+		Flags:        msg.Flags,
+		InternalDate: msg.InternalDate,
+		MessageID:    msg.MessageID,
+		Size:         int64(len(msg.Body)),
+	}
+	ff.offset += int64(buf.Len())
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := w.index.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入归档索引失败: %w", err)
+	}
+
+	ff.archived[msg.UID] = true
+	return nil
+}
+
+// Sync 将已写入的数据刷新到磁盘
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, ff := range w.folders {
+		if err := ff.file.Sync(); err != nil {
+			return fmt.Errorf("同步归档文件失败: %w", err)
+		}
+	}
+	if err := w.index.Sync(); err != nil {
+		return fmt.Errorf("同步归档索引失败: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭归档写入器
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var firstErr error
+	for _, ff := range w.folders {
+		if err := ff.file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if err := w.index.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	w.folders = nil
+	return firstErr
+}
+
+// folderFileLocked 获取或创建文件夹对应的 mbox 文件（需要持有锁）
+func (w *Writer) folderFileLocked(folder string) (*folderFile, error) {
+	if ff, ok := w.folders[folder]; ok {
+		return ff, nil
+	}
+
+	name := sanitizeFileName(folder) + mboxExt
+	for i := 2; w.names[name]; i++ {
+		name = fmt.Sprintf("%s-%d%s", sanitizeFileName(folder), i, mboxExt)
+	}
+
+	file, err := os.OpenFile(filepath.Join(w.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("创建归档文件失败: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	ff := &folderFile{
+		file:     file,
+		name:     name,
+		offset:   info.Size(),
+		archived: make(map[uint32]bool),
+	}
+	w.folders[folder] = ff
+	w.names[name] = true
+	return ff, nil
+}
+
+// ReadIndex 读取归档目录的索引
+func ReadIndex(dir string) ([]Entry, error) {
+	file, err := os.Open(filepath.Join(dir, indexFileName))
+	if err != nil {
+		return nil, fmt.Errorf("打开归档索引失败: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// 最后一行可能因中断而不完整，跳过
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取归档索引失败: %w", err)
+	}
+	return entries, nil
+}
+
+// ReadMessage 根据索引条目读取邮件原文
+func ReadMessage(dir string, entry Entry) ([]byte, error) {
+	file, err := os.Open(filepath.Join(dir, entry.File))
+	if err != nil {
+		return nil, fmt.Errorf("打开归档文件失败: %w", err)
+	}
+	defer file.Close()
+
+	buf := make([]byte, entry.Length)
+	if _, err := file.ReadAt(buf, entry.Offset); err != nil {
+		return nil, fmt.Errorf("读取归档邮件失败: %w", err)
+	}
+	return unescapeFromLines(buf), nil
+}
+
+// escapeFromLines 按 mboxrd 规则转义以 ">*From " 开头的行
+func escapeFromLines(body []byte) []byte {
+	if !bytes.Contains(body, []byte("From ")) {
+		return body
+	}
+	lines := bytes.SplitAfter(body, []byte("\n"))
+	var out bytes.Buffer
+	out.Grow(len(body) + 16)
+	for _, line := range lines {
+		if isFromLine(line) {
+			out.WriteByte('>')
+		}
+		out.Write(line)
+	}
+	return out.Bytes()
+}
+
+// unescapeFromLines 还原 escapeFromLines 的转义
+func unescapeFromLines(body []byte) []byte {
+	if !bytes.Contains(body, []byte(">From ")) {
+		return body
+	}
+	lines := bytes.SplitAfter(body, []byte("\n"))
+	var out bytes.Buffer
+	out.Grow(len(body))
+	for _, line := range lines {
+		if len(line) > 0 && line[0] == '>' && isFromLine(line[1:]) {
+			line = line[1:]
+		}
+		out.Write(line)
+	}
+	return out.Bytes()
+}
+
+// isFromLine 检查是否为 ">*From " 形式的行
+func isFromLine(line []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From "))
+}
+
+// sanitizeFileName 将文件夹名转换为安全的文件名
+func sanitizeFileName(name string) string {
+	replacer := strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_", "?", "_",
+		"\"", "_", "<", "_", ">", "_", "|", "_", ".", "_")
+	name = strings.TrimSpace(replacer.Replace(name))
+	if name == "" {
+		return "folder"
+	}
+	return name
+}
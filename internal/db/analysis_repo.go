@@ -0,0 +1,99 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"CleanMyEmail/internal/model"
+)
+
+// CreateAnalysisReport 保存邮箱分析报告
+func CreateAnalysisReport(report *model.AnalysisReport) (int64, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO analysis_reports (account_id, account_email, folder_count, total_count, total_size, status,
+			report_json, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, report.AccountID, report.AccountEmail, len(report.Folders), report.TotalCount, report.TotalSize,
+		report.Status, string(reportJSON), report.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetAnalysisReport 根据ID获取分析报告
+func GetAnalysisReport(id int64) (*model.AnalysisReport, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var reportJSON string
+	if err := db.QueryRow(`SELECT report_json FROM analysis_reports WHERE id = ?`, id).Scan(&reportJSON); err != nil {
+		return nil, err
+	}
+
+	report := &model.AnalysisReport{}
+	if err := json.Unmarshal([]byte(reportJSON), report); err != nil {
+		return nil, err
+	}
+	report.ID = id
+	return report, nil
+}
+
+// ListAnalysisReports 获取分析报告列表（按时间倒序），accountID 为 0 时返回所有账号的报告
+func ListAnalysisReports(accountID int64) ([]*model.AnalysisListItem, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows *sql.Rows
+	if accountID > 0 {
+		rows, err = db.Query(`
+			SELECT id, account_id, account_email, folder_count, total_count, total_size, status, created_at
+			FROM analysis_reports WHERE account_id = ? ORDER BY created_at DESC, id DESC
+		`, accountID)
+	} else {
+		rows, err = db.Query(`
+			SELECT id, account_id, account_email, folder_count, total_count, total_size, status, created_at
+			FROM analysis_reports ORDER BY created_at DESC, id DESC
+		`)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*model.AnalysisListItem
+	for rows.Next() {
+		item := &model.AnalysisListItem{}
+		if err := rows.Scan(&item.ID, &item.AccountID, &item.AccountEmail, &item.FolderCount, &item.TotalCount,
+			&item.TotalSize, &item.Status, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// DeleteAnalysisReport 删除分析报告
+func DeleteAnalysisReport(id int64) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`DELETE FROM analysis_reports WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"CleanMyEmail/internal/model"
+)
+
+// SaveDeletedMessages 批量写入删除审计记录，写入后设置各记录的 ID
+func SaveDeletedMessages(messages []*model.DeletedMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO deleted_messages (
+			history_id, account_id, account_email, folder, uid, message_id,
+			from_addr, to_addr, subject, sent_date, size, action, status, deleted_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, m := range messages {
+		var sentDate sql.NullTime
+		if !m.Date.IsZero() {
+			sentDate = sql.NullTime{Time: m.Date, Valid: true}
+		}
+		result, err := stmt.Exec(m.HistoryID, m.AccountID, m.AccountEmail, m.Folder, m.UID, m.MessageID,
+			m.From, m.To, m.Subject, sentDate, m.Size, m.Action, m.Status, m.DeletedAt)
+		if err != nil {
+			return err
+		}
+		if m.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UpdateDeletedMessagesStatus 批量更新删除审计记录的状态和删除时间
+func UpdateDeletedMessagesStatus(ids []int64, status string, deletedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`UPDATE deleted_messages SET status = ?, deleted_at = ? WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, id := range ids {
+		if _, err := stmt.Exec(status, deletedAt, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SearchDeletedMessages 按条件查询删除审计记录，按删除时间倒序；limit 小于 0 时不限数量
+func SearchDeletedMessages(q *model.DeletedMessageQuery, limit, offset int) ([]*model.DeletedMessage, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := deletedMessageWhere(q)
+	args = append(args, limit, offset)
+	rows, err := db.Query(`
+		SELECT id, history_id, account_id, account_email, folder, uid, message_id,
+			   from_addr, to_addr, subject, sent_date, size, action, status, deleted_at
+		FROM deleted_messages`+where+`
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*model.DeletedMessage
+	for rows.Next() {
+		m := &model.DeletedMessage{}
+		var sentDate sql.NullTime
+		if err := rows.Scan(&m.ID, &m.HistoryID, &m.AccountID, &m.AccountEmail, &m.Folder, &m.UID, &m.MessageID,
+			&m.From, &m.To, &m.Subject, &sentDate, &m.Size, &m.Action, &m.Status, &m.DeletedAt); err != nil {
+			return nil, err
+		}
+		if sentDate.Valid {
+			m.Date = sentDate.Time
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// CountDeletedMessages 统计符合条件的删除审计记录数量
+func CountDeletedMessages(q *model.DeletedMessageQuery) (int, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	where, args := deletedMessageWhere(q)
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM deleted_messages`+where, args...).Scan(&count)
+	return count, err
+}
+
+// deletedMessageWhere 构建删除审计记录查询的 WHERE 子句，发件人和主题按包含匹配（不区分大小写）
+func deletedMessageWhere(q *model.DeletedMessageQuery) (string, []any) {
+	var conds []string
+	var args []any
+	if q.AccountID > 0 {
+		conds = append(conds, "account_id = ?")
+		args = append(args, q.AccountID)
+	}
+	if q.HistoryID > 0 {
+		conds = append(conds, "history_id = ?")
+		args = append(args, q.HistoryID)
+	}
+	if q.Sender != "" {
+		conds = append(conds, `from_addr LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(q.Sender))
+	}
+	if q.Subject != "" {
+		conds = append(conds, `subject LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(q.Subject))
+	}
+	if q.MessageID != "" {
+		conds = append(conds, "message_id = ?")
+		args = append(args, q.MessageID)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// likePattern 生成包含匹配的 LIKE 模式，转义通配符
+func likePattern(text string) string {
+	text = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+	return "%" + text + "%"
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"time"
+
+	"CleanMyEmail/internal/model"
+)
+
+// SaveCheckpoint 保存或更新清理检查点
+func SaveCheckpoint(cp *model.CleanCheckpoint) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	// 先尝试更新
+	result, err := db.Exec(`
+		UPDATE clean_checkpoints
+		SET uid_validity = ?, remaining_uids = ?, stripped_uids = ?, matched_count = ?, deleted_count = ?, status = ?, updated_at = ?
+		WHERE history_id = ? AND folder = ?
+	`, cp.UIDValidity, cp.RemainingUIDs, cp.StrippedUIDs, cp.MatchedCount, cp.DeletedCount, cp.Status, time.Now(),
+		cp.HistoryID, cp.Folder)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	// 不存在则插入
+	_, err = db.Exec(`
+		INSERT INTO clean_checkpoints (history_id, folder, uid_validity, remaining_uids, stripped_uids, matched_count, deleted_count, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, cp.HistoryID, cp.Folder, cp.UIDValidity, cp.RemainingUIDs, cp.StrippedUIDs, cp.MatchedCount, cp.DeletedCount, cp.Status)
+	return err
+}
+
+// GetCheckpoints 获取清理任务的所有检查点
+func GetCheckpoints(historyID int64) ([]*model.CleanCheckpoint, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT history_id, folder, uid_validity, remaining_uids, stripped_uids, matched_count, deleted_count, status, updated_at
+		FROM clean_checkpoints WHERE history_id = ? ORDER BY id ASC
+	`, historyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checkpoints []*model.CleanCheckpoint
+	for rows.Next() {
+		cp := &model.CleanCheckpoint{}
+		err := rows.Scan(&cp.HistoryID, &cp.Folder, &cp.UIDValidity, &cp.RemainingUIDs, &cp.StrippedUIDs,
+			&cp.MatchedCount, &cp.DeletedCount, &cp.Status, &cp.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, rows.Err()
+}
+
+// DeleteCheckpoints 删除清理任务的所有检查点
+func DeleteCheckpoints(historyID int64) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DELETE FROM clean_checkpoints WHERE history_id = ?", historyID)
+	return err
+}
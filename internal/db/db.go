@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 	"sync"
 
 	"CleanMyEmail/internal/config"
@@ -72,10 +73,12 @@ func initTables() error {
 		folders         TEXT NOT NULL,
 		folder_count    INTEGER DEFAULT 0,
 		date_range      TEXT,
+		date_expression TEXT,
 		filter_sender   TEXT,
 		filter_subject  TEXT,
 		filter_size     TEXT,
 		filter_read     TEXT,
+		filter_query    TEXT,
 		matched_count   INTEGER DEFAULT 0,
 		deleted_count   INTEGER DEFAULT 0,
 		preview_only    INTEGER DEFAULT 0,
@@ -84,6 +87,126 @@ func initTables() error {
 		duration        REAL DEFAULT 0,
 		status          TEXT DEFAULT 'running',
 		error_message   TEXT,
+		archive_path    TEXT,
+		request_json    TEXT,
+		schedule_id     INTEGER,
+		exclusions      TEXT,
+		created_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (account_id) REFERENCES email_accounts(id) ON DELETE CASCADE
+	);
+
+	-- 清理检查点表（记录每个文件夹的删除进度，用于中断后恢复）
+	CREATE TABLE IF NOT EXISTS clean_checkpoints (
+		id              INTEGER PRIMARY KEY AUTOINCREMENT,
+		history_id      INTEGER NOT NULL,
+		folder          TEXT NOT NULL,
+		uid_validity    INTEGER DEFAULT 0,
+		remaining_uids  TEXT,
+		stripped_uids   TEXT DEFAULT '',
+		matched_count   INTEGER DEFAULT 0,
+		deleted_count   INTEGER DEFAULT 0,
+		status          TEXT DEFAULT 'running',
+		updated_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (history_id, folder),
+		FOREIGN KEY (history_id) REFERENCES clean_history(id) ON DELETE CASCADE
+	);
+
+	-- 删除审计表（每封被删除的邮件一条，history_id 对应 clean_history.id；删除历史记录时保留）
+	CREATE TABLE IF NOT EXISTS deleted_messages (
+		id              INTEGER PRIMARY KEY AUTOINCREMENT,
+		history_id      INTEGER NOT NULL,
+		account_id      INTEGER NOT NULL,
+		account_email   TEXT NOT NULL,
+		folder          TEXT NOT NULL,
+		uid             INTEGER NOT NULL,
+		message_id      TEXT DEFAULT '',
+		from_addr       TEXT DEFAULT '',
+		to_addr         TEXT DEFAULT '',
+		subject         TEXT DEFAULT '',
+		sent_date       DATETIME,
+		size            INTEGER DEFAULT 0,
+		action          TEXT NOT NULL,
+		status          TEXT DEFAULT 'deleted',
+		deleted_at      DATETIME NOT NULL
+	);
+
+	-- 定时清理计划表
+	CREATE TABLE IF NOT EXISTS clean_schedules (
+		id              INTEGER PRIMARY KEY AUTOINCREMENT,
+		name            TEXT NOT NULL,
+		account_id      INTEGER NOT NULL,
+		schedule_type   TEXT NOT NULL,
+		spec            TEXT NOT NULL,
+		request_json    TEXT NOT NULL,
+		older_than_days INTEGER DEFAULT 0,
+		window_days     INTEGER DEFAULT 0,
+		enabled         INTEGER DEFAULT 1,
+		next_run_at     DATETIME,
+		last_run_at     DATETIME,
+		last_status     TEXT,
+		last_history_id INTEGER DEFAULT 0,
+		created_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (account_id) REFERENCES email_accounts(id) ON DELETE CASCADE
+	);
+
+	-- 清理条件预设表
+	CREATE TABLE IF NOT EXISTS clean_presets (
+		id              INTEGER PRIMARY KEY AUTOINCREMENT,
+		name            TEXT NOT NULL UNIQUE,
+		folders         TEXT NOT NULL,
+		filter_sender   TEXT DEFAULT '',
+		filter_subject  TEXT DEFAULT '',
+		filter_size     TEXT DEFAULT '',
+		filter_read     TEXT DEFAULT '',
+		filter_query    TEXT DEFAULT '',
+		older_than      TEXT DEFAULT '',
+		date_between    TEXT DEFAULT '',
+		date_basis      TEXT DEFAULT '',
+		batch_size      INTEGER DEFAULT 0,
+		adaptive_batch  INTEGER DEFAULT 0,
+		max_concurrency INTEGER DEFAULT 0,
+		created_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at      DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- 清理计划表（预览确定的待删除邮件，执行时只删除这些 UID）
+	CREATE TABLE IF NOT EXISTS clean_plans (
+		id                  INTEGER PRIMARY KEY AUTOINCREMENT,
+		account_id          INTEGER NOT NULL,
+		account_email       TEXT NOT NULL,
+		preview_history_id  INTEGER DEFAULT 0,
+		request_json        TEXT NOT NULL,
+		total_count         INTEGER DEFAULT 0,
+		status              TEXT DEFAULT 'pending',
+		executed_history_id INTEGER DEFAULT 0,
+		expires_at          DATETIME NOT NULL,
+		created_at          DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (account_id) REFERENCES email_accounts(id) ON DELETE CASCADE
+	);
+
+	-- 清理计划文件夹表（每个文件夹的 UIDVALIDITY 和 UID 集合）
+	CREATE TABLE IF NOT EXISTS clean_plan_folders (
+		id              INTEGER PRIMARY KEY AUTOINCREMENT,
+		plan_id         INTEGER NOT NULL,
+		folder          TEXT NOT NULL,
+		uid_validity    INTEGER DEFAULT 0,
+		uids            TEXT NOT NULL,
+		count           INTEGER DEFAULT 0,
+		UNIQUE (plan_id, folder),
+		FOREIGN KEY (plan_id) REFERENCES clean_plans(id) ON DELETE CASCADE
+	);
+
+	-- 邮箱分析报告表（统计结果以 JSON 保存）
+	CREATE TABLE IF NOT EXISTS analysis_reports (
+		id              INTEGER PRIMARY KEY AUTOINCREMENT,
+		account_id      INTEGER NOT NULL,
+		account_email   TEXT NOT NULL,
+		folder_count    INTEGER DEFAULT 0,
+		total_count     INTEGER DEFAULT 0,
+		total_size      INTEGER DEFAULT 0,
+		status          TEXT DEFAULT 'completed',
+		report_json     TEXT NOT NULL,
 		created_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (account_id) REFERENCES email_accounts(id) ON DELETE CASCADE
 	);
@@ -110,6 +233,13 @@ func initTables() error {
 	-- 创建索引
 	CREATE INDEX IF NOT EXISTS idx_oauth2_tokens_account_id ON oauth2_tokens(account_id);
 	CREATE INDEX IF NOT EXISTS idx_clean_history_account_id ON clean_history(account_id);
+	CREATE INDEX IF NOT EXISTS idx_clean_checkpoints_history_id ON clean_checkpoints(history_id);
+	CREATE INDEX IF NOT EXISTS idx_deleted_messages_history_id ON deleted_messages(history_id);
+	CREATE INDEX IF NOT EXISTS idx_deleted_messages_message_id ON deleted_messages(message_id);
+	CREATE INDEX IF NOT EXISTS idx_deleted_messages_account_deleted_at ON deleted_messages(account_id, deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_clean_schedules_account_id ON clean_schedules(account_id);
+	CREATE INDEX IF NOT EXISTS idx_clean_plan_folders_plan_id ON clean_plan_folders(plan_id);
+	CREATE INDEX IF NOT EXISTS idx_analysis_reports_account_id ON analysis_reports(account_id);
 	`
 
 	_, err := db.Exec(createTableSQL)
@@ -117,11 +247,78 @@ func initTables() error {
 		return err
 	}
 
+	if err := migrateTables(); err != nil {
+		return err
+	}
+
 	// 初始化默认 OAuth2 配置（如果不存在）
 	initDefaultOAuth2Configs()
 	return nil
 }
 
+// migrateTables 为旧版本数据库补充新增的列
+func migrateTables() error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"clean_history", "archive_path", "TEXT"},
+		{"clean_history", "request_json", "TEXT"},
+		{"clean_history", "schedule_id", "INTEGER"},
+		{"clean_history", "filter_query", "TEXT"},
+		{"clean_presets", "filter_query", "TEXT DEFAULT ''"},
+		{"clean_history", "exclusions", "TEXT"},
+		{"clean_history", "date_expression", "TEXT"},
+		{"clean_presets", "older_than", "TEXT DEFAULT ''"},
+		{"clean_presets", "date_between", "TEXT DEFAULT ''"},
+		{"clean_presets", "date_basis", "TEXT DEFAULT ''"},
+		{"clean_presets", "adaptive_batch", "INTEGER DEFAULT 0"},
+		{"deleted_messages", "status", "TEXT DEFAULT 'deleted'"},
+		{"clean_checkpoints", "stripped_uids", "TEXT DEFAULT ''"},
+	}
+
+	for _, c := range columns {
+		if err := addColumnIfNotExists(c.table, c.column, c.definition); err != nil {
+			return fmt.Errorf("迁移 %s.%s 失败: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
+
+// addColumnIfNotExists 列不存在时添加列
+func addColumnIfNotExists(table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
 // initDefaultOAuth2Configs 初始化默认 OAuth2 配置
 func initDefaultOAuth2Configs() {
 	defaultConfigs := []struct {
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"CleanMyEmail/internal/model"
+)
+
+// CreatePlan 创建清理计划（含各文件夹的 UID 集合）
+func CreatePlan(plan *model.CleanPlan) (int64, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	requestJSON, err := json.Marshal(plan.Request)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO clean_plans (account_id, account_email, preview_history_id, request_json, total_count, status, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, plan.AccountID, plan.AccountEmail, plan.PreviewHistoryID, string(requestJSON), plan.TotalCount,
+		plan.Status, plan.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, f := range plan.Folders {
+		if _, err := tx.Exec(`
+			INSERT INTO clean_plan_folders (plan_id, folder, uid_validity, uids, count)
+			VALUES (?, ?, ?, ?, ?)
+		`, id, f.Folder, f.UIDValidity, f.UIDs, f.Count); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// GetPlan 根据ID获取清理计划
+func GetPlan(id int64) (*model.CleanPlan, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &model.CleanPlan{}
+	var requestJSON string
+	err = db.QueryRow(`
+		SELECT id, account_id, account_email, preview_history_id, request_json, total_count, status,
+			executed_history_id, expires_at, created_at
+		FROM clean_plans WHERE id = ?
+	`, id).Scan(&plan.ID, &plan.AccountID, &plan.AccountEmail, &plan.PreviewHistoryID, &requestJSON,
+		&plan.TotalCount, &plan.Status, &plan.ExecutedHistoryID, &plan.ExpiresAt, &plan.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(requestJSON), &plan.Request); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT folder, uid_validity, uids, count FROM clean_plan_folders WHERE plan_id = ? ORDER BY id ASC
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		f := &model.CleanPlanFolder{}
+		if err := rows.Scan(&f.Folder, &f.UIDValidity, &f.UIDs, &f.Count); err != nil {
+			return nil, err
+		}
+		plan.Folders = append(plan.Folders, f)
+	}
+	return plan, rows.Err()
+}
+
+// GetPlanIDByExecutedHistory 获取由指定清理历史执行的计划 ID，没有时返回 sql.ErrNoRows
+func GetPlanIDByExecutedHistory(historyID int64) (int64, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = db.QueryRow(`SELECT id FROM clean_plans WHERE executed_history_id = ?`, historyID).Scan(&id)
+	return id, err
+}
+
+// UpdatePlanRequest 更新待执行计划的清理请求（如排除项），计划已不是待执行状态时返回 sql.ErrNoRows
+func UpdatePlanRequest(id int64, req *model.CleanRequest) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	requestJSON, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Exec(`
+		UPDATE clean_plans SET request_json = ? WHERE id = ? AND status = ?
+	`, string(requestJSON), id, model.PlanStatusPending)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MarkPlanExecuted 将计划标记为已执行，计划已不是待执行状态时返回 sql.ErrNoRows
+func MarkPlanExecuted(id, historyID int64) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Exec(`
+		UPDATE clean_plans SET status = ?, executed_history_id = ? WHERE id = ? AND status = ?
+	`, model.PlanStatusExecuted, historyID, id, model.PlanStatusPending)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ReleasePlan 将由 historyID 标记为已执行的计划恢复为待执行（执行任务未能开始时使用）
+func ReleasePlan(id, historyID int64) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		UPDATE clean_plans SET status = ?, executed_history_id = 0 WHERE id = ? AND status = ? AND executed_history_id = ?
+	`, model.PlanStatusPending, id, model.PlanStatusExecuted, historyID)
+	return err
+}
+
+// DeleteExpiredPlans 删除已过期的计划
+// 在内存中比较时间，避免 SQLite 按字符串比较不同格式的时间
+func DeleteExpiredPlans(now time.Time) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query("SELECT id, expires_at FROM clean_plans")
+	if err != nil {
+		return err
+	}
+	var expired []int64
+	for rows.Next() {
+		var id int64
+		var expiresAt time.Time
+		if err := rows.Scan(&id, &expiresAt); err != nil {
+			rows.Close()
+			return err
+		}
+		if now.After(expiresAt) {
+			expired = append(expired, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range expired {
+		if _, err := db.Exec("DELETE FROM clean_plan_folders WHERE plan_id = ?", id); err != nil {
+			return err
+		}
+		if _, err := db.Exec("DELETE FROM clean_plans WHERE id = ?", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
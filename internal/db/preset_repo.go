@@ -0,0 +1,147 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+
+	"CleanMyEmail/internal/model"
+)
+
+// CreatePreset 创建清理预设
+func CreatePreset(preset *model.CleanPreset) (int64, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	foldersJSON, err := json.Marshal(preset.Folders)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO clean_presets (name, folders, filter_sender, filter_subject, filter_size, filter_read,
+			filter_query, older_than, date_between, date_basis, batch_size, adaptive_batch, max_concurrency)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, preset.Name, string(foldersJSON), preset.FilterSender, preset.FilterSubject, preset.FilterSize,
+		preset.FilterRead, preset.FilterQuery, preset.OlderThan, preset.Between, preset.DateBasis,
+		preset.BatchSize, preset.AdaptiveBatch, preset.MaxConcurrency)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// UpdatePreset 更新清理预设
+func UpdatePreset(preset *model.CleanPreset) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	foldersJSON, err := json.Marshal(preset.Folders)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		UPDATE clean_presets
+		SET name = ?, folders = ?, filter_sender = ?, filter_subject = ?, filter_size = ?, filter_read = ?,
+			filter_query = ?, older_than = ?, date_between = ?, date_basis = ?, batch_size = ?, adaptive_batch = ?, max_concurrency = ?,
+			updated_at = ?
+		WHERE id = ?
+	`, preset.Name, string(foldersJSON), preset.FilterSender, preset.FilterSubject, preset.FilterSize,
+		preset.FilterRead, preset.FilterQuery, preset.OlderThan, preset.Between, preset.DateBasis,
+		preset.BatchSize, preset.AdaptiveBatch, preset.MaxConcurrency, time.Now(), preset.ID)
+	return err
+}
+
+// GetPreset 根据ID获取清理预设
+func GetPreset(id int64) (*model.CleanPreset, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	row := db.QueryRow(`
+		SELECT id, name, folders, filter_sender, filter_subject, filter_size, filter_read,
+			COALESCE(filter_query, ''), COALESCE(older_than, ''), COALESCE(date_between, ''), COALESCE(date_basis, ''),
+			batch_size, adaptive_batch, max_concurrency, created_at, updated_at
+		FROM clean_presets WHERE id = ?
+	`, id)
+	return scanPreset(row)
+}
+
+// GetPresetByName 根据名称获取清理预设
+func GetPresetByName(name string) (*model.CleanPreset, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	row := db.QueryRow(`
+		SELECT id, name, folders, filter_sender, filter_subject, filter_size, filter_read,
+			COALESCE(filter_query, ''), COALESCE(older_than, ''), COALESCE(date_between, ''), COALESCE(date_basis, ''),
+			batch_size, adaptive_batch, max_concurrency, created_at, updated_at
+		FROM clean_presets WHERE name = ?
+	`, name)
+	return scanPreset(row)
+}
+
+// ListPresets 获取所有清理预设
+func ListPresets() ([]*model.CleanPreset, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT id, name, folders, filter_sender, filter_subject, filter_size, filter_read,
+			COALESCE(filter_query, ''), COALESCE(older_than, ''), COALESCE(date_between, ''), COALESCE(date_basis, ''),
+			batch_size, adaptive_batch, max_concurrency, created_at, updated_at
+		FROM clean_presets ORDER BY name ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var presets []*model.CleanPreset
+	for rows.Next() {
+		preset, err := scanPreset(rows)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, preset)
+	}
+	return presets, rows.Err()
+}
+
+// DeletePreset 删除清理预设
+func DeletePreset(id int64) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DELETE FROM clean_presets WHERE id = ?", id)
+	return err
+}
+
+// scanPreset 解析一行预设数据
+func scanPreset(row interface{ Scan(dest ...any) error }) (*model.CleanPreset, error) {
+	preset := &model.CleanPreset{}
+	var foldersJSON string
+
+	err := row.Scan(&preset.ID, &preset.Name, &foldersJSON, &preset.FilterSender, &preset.FilterSubject,
+		&preset.FilterSize, &preset.FilterRead, &preset.FilterQuery, &preset.OlderThan, &preset.Between,
+		&preset.DateBasis, &preset.BatchSize, &preset.AdaptiveBatch, &preset.MaxConcurrency, &preset.CreatedAt, &preset.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(foldersJSON), &preset.Folders); err != nil {
+		return nil, err
+	}
+	return preset, nil
+}
@@ -0,0 +1,197 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"CleanMyEmail/internal/model"
+)
+
+const scheduleColumns = `
+	s.id, s.name, s.account_id, COALESCE(a.email, ''), s.schedule_type, s.spec, s.request_json,
+	s.older_than_days, s.window_days, s.enabled, s.next_run_at, s.last_run_at,
+	COALESCE(s.last_status, ''), s.last_history_id, s.created_at, s.updated_at`
+
+// CreateSchedule 创建定时清理计划
+func CreateSchedule(schedule *model.CleanSchedule) (int64, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	requestJSON, err := json.Marshal(schedule.Request)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO clean_schedules (name, account_id, schedule_type, spec, request_json,
+			older_than_days, window_days, enabled, next_run_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, schedule.Name, schedule.AccountID, schedule.ScheduleType, schedule.Spec, string(requestJSON),
+		schedule.OlderThanDays, schedule.WindowDays, schedule.Enabled, schedule.NextRunAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// UpdateSchedule 更新定时清理计划的配置
+func UpdateSchedule(schedule *model.CleanSchedule) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	requestJSON, err := json.Marshal(schedule.Request)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		UPDATE clean_schedules
+		SET name = ?, account_id = ?, schedule_type = ?, spec = ?, request_json = ?,
+			older_than_days = ?, window_days = ?, enabled = ?, next_run_at = ?, updated_at = ?
+		WHERE id = ?
+	`, schedule.Name, schedule.AccountID, schedule.ScheduleType, schedule.Spec, string(requestJSON),
+		schedule.OlderThanDays, schedule.WindowDays, schedule.Enabled, schedule.NextRunAt, time.Now(),
+		schedule.ID)
+	return err
+}
+
+// GetSchedule 根据ID获取定时清理计划
+func GetSchedule(id int64) (*model.CleanSchedule, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	row := db.QueryRow(`
+		SELECT `+scheduleColumns+`
+		FROM clean_schedules s LEFT JOIN email_accounts a ON a.id = s.account_id
+		WHERE s.id = ?
+	`, id)
+	return scanSchedule(row)
+}
+
+// ListSchedules 获取所有定时清理计划
+func ListSchedules() ([]*model.CleanSchedule, error) {
+	return querySchedules(`
+		SELECT ` + scheduleColumns + `
+		FROM clean_schedules s LEFT JOIN email_accounts a ON a.id = s.account_id
+		ORDER BY s.id ASC
+	`)
+}
+
+// ListDueSchedules 获取已到执行时间的启用计划
+// 在内存中比较时间，避免 SQLite 按字符串比较不同格式的时间
+func ListDueSchedules(now time.Time) ([]*model.CleanSchedule, error) {
+	schedules, err := querySchedules(`
+		SELECT ` + scheduleColumns + `
+		FROM clean_schedules s LEFT JOIN email_accounts a ON a.id = s.account_id
+		WHERE s.enabled = 1 AND s.next_run_at IS NOT NULL
+		ORDER BY s.id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []*model.CleanSchedule
+	for _, schedule := range schedules {
+		if !schedule.NextRunAt.After(now) {
+			due = append(due, schedule)
+		}
+	}
+	return due, nil
+}
+
+// UpdateScheduleNextRun 更新计划的下次执行时间
+func UpdateScheduleNextRun(id int64, nextRunAt *time.Time) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("UPDATE clean_schedules SET next_run_at = ? WHERE id = ?", nextRunAt, id)
+	return err
+}
+
+// UpdateScheduleLastRun 记录计划最近一次执行的结果
+func UpdateScheduleLastRun(id int64, runAt time.Time, status string, historyID int64) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		UPDATE clean_schedules SET last_run_at = ?, last_status = ?, last_history_id = ? WHERE id = ?
+	`, runAt, status, historyID, id)
+	return err
+}
+
+// DeleteSchedule 删除定时清理计划，已产生的历史记录保留但不再关联该计划
+func DeleteSchedule(id int64) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec("UPDATE clean_history SET schedule_id = NULL WHERE schedule_id = ?", id); err != nil {
+		return err
+	}
+	_, err = db.Exec("DELETE FROM clean_schedules WHERE id = ?", id)
+	return err
+}
+
+// querySchedules 执行查询并解析计划列表
+func querySchedules(query string, args ...any) ([]*model.CleanSchedule, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*model.CleanSchedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+// scanSchedule 解析一行计划数据
+func scanSchedule(row interface{ Scan(dest ...any) error }) (*model.CleanSchedule, error) {
+	schedule := &model.CleanSchedule{}
+	var requestJSON string
+	var enabled int
+	var nextRunAt, lastRunAt sql.NullTime
+
+	err := row.Scan(&schedule.ID, &schedule.Name, &schedule.AccountID, &schedule.AccountEmail,
+		&schedule.ScheduleType, &schedule.Spec, &requestJSON,
+		&schedule.OlderThanDays, &schedule.WindowDays, &enabled, &nextRunAt, &lastRunAt,
+		&schedule.LastStatus, &schedule.LastHistoryID, &schedule.CreatedAt, &schedule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(requestJSON), &schedule.Request); err != nil {
+		return nil, err
+	}
+	schedule.Enabled = enabled == 1
+	if nextRunAt.Valid {
+		schedule.NextRunAt = &nextRunAt.Time
+	}
+	if lastRunAt.Valid {
+		schedule.LastRunAt = &lastRunAt.Time
+	}
+	return schedule, nil
+}
@@ -3,6 +3,8 @@ package db
 import (
 	"database/sql"
 	"encoding/json"
+	"log"
+	"time"
 
 	"CleanMyEmail/internal/model"
 )
@@ -83,3 +85,79 @@ func SaveProxySettings(proxy *model.ProxySettings) error {
 	return SaveAppSettings(settings)
 }
 
+// GetProtectedSenders 获取受保护的发件人
+func GetProtectedSenders() (*model.ProtectedSenders, error) {
+	settings, err := GetAppSettings()
+	if err != nil {
+		return nil, err
+	}
+	return &settings.ProtectedSenders, nil
+}
+
+// SaveProtectedSenders 保存受保护的发件人
+func SaveProtectedSenders(protected *model.ProtectedSenders) error {
+	settings, err := GetAppSettings()
+	if err != nil {
+		return err
+	}
+	settings.ProtectedSenders = *protected
+	return SaveAppSettings(settings)
+}
+
+// GetTimezone 获取用户设置的时区名，为空表示使用系统时区
+func GetTimezone() (string, error) {
+	settings, err := GetAppSettings()
+	if err != nil {
+		return "", err
+	}
+	return settings.Timezone, nil
+}
+
+// SaveTimezone 保存用户设置的时区名
+func SaveTimezone(timezone string) error {
+	settings, err := GetAppSettings()
+	if err != nil {
+		return err
+	}
+	settings.Timezone = timezone
+	return SaveAppSettings(settings)
+}
+
+// GetLocation 获取用户设置的时区，用于解析相对日期；读取失败时使用系统时区
+func GetLocation() *time.Location {
+	settings, err := GetAppSettings()
+	if err != nil {
+		log.Printf("[WARN] 读取时区设置失败，使用系统时区: %v", err)
+		return time.Local
+	}
+	return settings.Location()
+}
+
+// GetRateLimits 获取用户覆盖的限速设置，按邮箱厂商
+func GetRateLimits() (map[model.EmailVendorType]model.RateLimit, error) {
+	settings, err := GetAppSettings()
+	if err != nil {
+		return nil, err
+	}
+	return settings.RateLimits, nil
+}
+
+// SaveRateLimits 保存用户覆盖的限速设置
+func SaveRateLimits(limits map[model.EmailVendorType]model.RateLimit) error {
+	settings, err := GetAppSettings()
+	if err != nil {
+		return err
+	}
+	settings.RateLimits = limits
+	return SaveAppSettings(settings)
+}
+
+// GetRateLimit 获取厂商实际使用的限速，读取设置失败时使用默认值
+func GetRateLimit(vendor model.EmailVendorType) model.RateLimit {
+	settings, err := GetAppSettings()
+	if err != nil {
+		log.Printf("[WARN] 读取限速设置失败，使用默认值: %v", err)
+		return vendor.DefaultRateLimit()
+	}
+	return settings.RateLimitFor(vendor)
+}
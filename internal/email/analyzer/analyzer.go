@@ -0,0 +1,484 @@
+package analyzer
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+
+	"CleanMyEmail/internal/db"
+	imapClient "CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/model"
+)
+
+const (
+	maxRetries         = 3               // 最大重试次数
+	retryInterval      = 2 * time.Second // 重试间隔
+	fetchBatchSize     = 200             // 每批获取的邮件数
+	defaultConcurrency = 3               // 默认并发文件夹数
+	maxReportRows      = 500             // 发件人和域名统计保留的最大行数
+	maxThrottleRetries = 5               // 服务器限流时的最大重试次数（不计入 maxRetries）
+)
+
+// Analyzer 邮箱分析器，按发件人、域名、月份和文件夹统计邮件数量和占用空间
+type Analyzer struct {
+	pool       *imapClient.ConnectionPool
+	ctx        context.Context
+	cancel     context.CancelFunc
+	progressCh chan *model.AnalysisProgress
+	mu         sync.Mutex
+	running    bool
+	accountID  int64
+	scanned    int            // 已统计的邮件数（各文件夹并发累加）
+	loc        *time.Location // 按月统计使用的用户时区，与清理请求解析日期的时区一致
+	// LargestMessages 结果中各文件夹的 UID，用于生成清理计划
+	planFolders []*model.CleanPlanFolder
+}
+
+// NewAnalyzer 创建分析器（使用外部连接池）
+func NewAnalyzer(pool *imapClient.ConnectionPool) *Analyzer {
+	return &Analyzer{
+		pool:       pool,
+		progressCh: make(chan *model.AnalysisProgress, 100),
+	}
+}
+
+// ProgressChan 获取进度通道
+func (a *Analyzer) ProgressChan() <-chan *model.AnalysisProgress {
+	return a.progressCh
+}
+
+// Cancel 取消分析
+func (a *Analyzer) Cancel() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cancel != nil {
+		a.cancel()
+	}
+}
+
+// stats 统计累加器
+type stats struct {
+	bySender map[string]*model.AnalysisRow
+	byDomain map[string]*model.AnalysisRow
+	byMonth  map[string]*model.AnalysisRow
+	byFolder map[string]*model.AnalysisRow
+	loc      *time.Location // 按月统计使用的时区
+}
+
+func newStats(loc *time.Location) *stats {
+	return &stats{
+		loc:      loc,
+		bySender: make(map[string]*model.AnalysisRow),
+		byDomain: make(map[string]*model.AnalysisRow),
+		byMonth:  make(map[string]*model.AnalysisRow),
+		byFolder: make(map[string]*model.AnalysisRow),
+	}
+}
+
+// add 累加一封邮件
+func (s *stats) add(folder, sender string, date time.Time, size int64) {
+	domain := model.AnalysisUnknownKey
+	if sender == "" {
+		sender = model.AnalysisUnknownKey
+	} else if _, d, ok := strings.Cut(sender, "@"); ok && d != "" {
+		domain = d
+	}
+	month := model.AnalysisUnknownKey
+	if !date.IsZero() {
+		month = date.In(s.loc).Format("2006-01")
+	}
+	addRow(s.bySender, sender, 1, size)
+	addRow(s.byDomain, domain, 1, size)
+	addRow(s.byMonth, month, 1, size)
+	addRow(s.byFolder, folder, 1, size)
+}
+
+// merge 合并另一个文件夹的统计
+func (s *stats) merge(other *stats) {
+	for _, pair := range [][2]map[string]*model.AnalysisRow{
+		{s.bySender, other.bySender},
+		{s.byDomain, other.byDomain},
+		{s.byMonth, other.byMonth},
+		{s.byFolder, other.byFolder},
+	} {
+		for key, row := range pair[1] {
+			addRow(pair[0], key, row.Count, row.Size)
+		}
+	}
+}
+
+func addRow(rows map[string]*model.AnalysisRow, key string, count int, size int64) {
+	row, ok := rows[key]
+	if !ok {
+		row = &model.AnalysisRow{Key: key}
+		rows[key] = row
+	}
+	row.Count += count
+	row.Size += size
+}
+
+// start 标记任务开始，同一时间只能执行一个任务
+func (a *Analyzer) start(accountID int64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.running {
+		return fmt.Errorf("分析任务正在进行中")
+	}
+	a.running = true
+	a.accountID = accountID
+	a.scanned = 0
+	a.loc = db.GetLocation()
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+	return nil
+}
+
+// finish 标记任务结束并关闭进度通道
+func (a *Analyzer) finish() {
+	a.mu.Lock()
+	a.running = false
+	a.mu.Unlock()
+	close(a.progressCh)
+}
+
+// addScanned 累加已扫描的邮件数，返回累加后的总数
+func (a *Analyzer) addScanned(n int) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.scanned += n
+	return a.scanned
+}
+
+// Analyze 执行分析，返回的报告尚未保存
+func (a *Analyzer) Analyze(req *model.AnalysisRequest) (*model.AnalysisReport, error) {
+	if err := a.start(req.AccountID); err != nil {
+		return nil, err
+	}
+	defer a.finish()
+
+	if len(req.Folders) == 0 {
+		return nil, fmt.Errorf("请选择要分析的文件夹")
+	}
+	criteria, err := buildCriteria(req)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	report := &model.AnalysisReport{
+		AccountID: req.AccountID,
+		Folders:   req.Folders,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Status:    "completed",
+	}
+
+	concurrency := req.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	total := newStats(a.loc)
+	var errs []string
+	var wg sync.WaitGroup
+	var mergeMu sync.Mutex
+	sem := make(chan struct{}, concurrency)
+	for i, folder := range req.Folders {
+		if a.ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(idx int, folder string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-a.ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			folderStats, err := a.analyzeFolder(folder, criteria, idx, len(req.Folders), startTime)
+			mergeMu.Lock()
+			defer mergeMu.Unlock()
+			if err != nil {
+				if a.ctx.Err() == nil {
+					log.Printf("[WARN] [%s] 分析失败: %v", folder, err)
+					errs = append(errs, fmt.Sprintf("%s: %v", folder, err))
+				}
+				return
+			}
+			total.merge(folderStats)
+		}(i, folder)
+	}
+	wg.Wait()
+
+	switch {
+	case a.ctx.Err() != nil:
+		report.Status = "cancelled"
+	case len(errs) > 0:
+		report.Status = "failed"
+		report.Error = strings.Join(errs, "; ")
+	}
+
+	for _, row := range total.byFolder {
+		report.TotalCount += row.Count
+		report.TotalSize += row.Size
+	}
+	report.BySender = sortRows(total.bySender, maxReportRows)
+	report.ByDomain = sortRows(total.byDomain, maxReportRows)
+	report.ByFolder = sortRows(total.byFolder, 0)
+	report.ByMonth = sortRowsByKey(total.byMonth)
+	report.Duration = time.Since(startTime).Seconds()
+
+	a.sendProgress(&model.AnalysisProgress{
+		AccountID:      req.AccountID,
+		TotalFolders:   len(req.Folders),
+		ScannedCount:   report.TotalCount,
+		Status:         report.Status,
+		Message:        fmt.Sprintf("分析完成，共统计 %d 封邮件", report.TotalCount),
+		ElapsedSeconds: report.Duration,
+	})
+	return report, nil
+}
+
+// buildCriteria 构建日期范围的搜索条件，未设置日期时搜索全部邮件
+func buildCriteria(req *model.AnalysisRequest) (*imap.SearchCriteria, error) {
+	criteria := &imap.SearchCriteria{}
+	if req.StartDate != "" {
+		start, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("开始日期格式错误: %w", err)
+		}
+		criteria.Since = start
+	}
+	if req.EndDate != "" {
+		end, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("结束日期格式错误: %w", err)
+		}
+		criteria.Before = end.AddDate(0, 0, 1) // BEFORE 是"严格早于"，需要 +1 天
+	}
+	return criteria, nil
+}
+
+// analyzeFolder 分析单个文件夹：搜索后分批获取信封和大小
+func (a *Analyzer) analyzeFolder(folder string, criteria *imap.SearchCriteria, folderIdx, totalFolders int, startTime time.Time) (*stats, error) {
+	conn, err := a.getConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if conn != nil {
+			conn.Release()
+		}
+	}()
+
+	if _, err := conn.Client().Select(folder, &imap.SelectOptions{ReadOnly: true}).Wait(); err != nil {
+		conn.MarkBad()
+		return nil, fmt.Errorf("选择文件夹失败: %w", err)
+	}
+	if err := a.throttle(); err != nil {
+		return nil, err
+	}
+	searchData, err := conn.Client().UIDSearch(criteria, nil).Wait()
+	if err != nil {
+		conn.MarkBad()
+		return nil, fmt.Errorf("搜索邮件失败: %w", err)
+	}
+	uids := searchData.AllUIDs()
+
+	result := newStats(a.loc)
+	for i := 0; i < len(uids); i += fetchBatchSize {
+		if a.ctx.Err() != nil {
+			return nil, fmt.Errorf("操作已取消")
+		}
+
+		end := min(i+fetchBatchSize, len(uids))
+		uidSet := imap.UIDSet{}
+		uidSet.AddNum(uids[i:end]...)
+
+		var batch *stats
+		conn, err = a.retryWithReconnect(conn, folder, func(cli *imapclient.Client) error {
+			batch = newStats(a.loc)
+			return fetchBatch(cli, folder, uidSet, batch)
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.merge(batch)
+
+		a.sendProgress(&model.AnalysisProgress{
+			AccountID:      a.accountID,
+			CurrentFolder:  folder,
+			FolderIndex:    folderIdx + 1,
+			TotalFolders:   totalFolders,
+			ScannedCount:   a.addScanned(end - i),
+			Status:         "running",
+			Message:        fmt.Sprintf("文件夹 %s: %d/%d 已统计", folder, end, len(uids)),
+			ElapsedSeconds: time.Since(startTime).Seconds(),
+		})
+	}
+	// 空文件夹也出现在按文件夹统计中
+	if len(uids) == 0 {
+		result.byFolder[folder] = &model.AnalysisRow{Key: folder}
+	}
+	return result, nil
+}
+
+// retryWithReconnect 带重连的 IMAP 操作，失败时换一个连接重新选择文件夹后重试
+// 每次执行前等待限速；服务器限流时退避后在同一连接上重试
+// 返回当前可用的连接；连接无法恢复时返回 nil
+func (a *Analyzer) retryWithReconnect(conn *imapClient.PooledConn, folder string, op func(cli *imapclient.Client) error) (*imapClient.PooledConn, error) {
+	var lastErr error
+	throttled := 0
+	for retry := 0; retry < maxRetries; retry++ {
+		if err := a.throttle(); err != nil {
+			return conn, err
+		}
+
+		if lastErr = op(conn.Client()); lastErr == nil {
+			return conn, nil
+		}
+
+		if limiter := a.pool.Limiter(); limiter != nil && imapClient.IsThrottled(lastErr) && throttled < maxThrottleRetries {
+			throttled++
+			retry--
+			log.Printf("[DEBUG] [%s] 服务器限流，%v 后重试 (%d/%d): %v", folder, limiter.Backoff(), throttled, maxThrottleRetries, lastErr)
+			continue
+		}
+
+		if retry < maxRetries-1 {
+			log.Printf("[DEBUG] [%s] 获取邮件失败，%v 后重试 (%d/%d): %v", folder, retryInterval, retry+1, maxRetries, lastErr)
+			time.Sleep(retryInterval)
+
+			conn.MarkBad()
+			var err error
+			if conn, err = a.getConnection(); err != nil {
+				return nil, fmt.Errorf("重新获取连接失败: %w", err)
+			}
+			if _, err := conn.Client().Select(folder, &imap.SelectOptions{ReadOnly: true}).Wait(); err != nil {
+				lastErr = err
+			}
+		}
+	}
+	return conn, fmt.Errorf("获取邮件失败，已重试 %d 次: %w", maxRetries, lastErr)
+}
+
+// throttle 执行 IMAP 命令前等待限速器的令牌，分析取消时返回错误
+func (a *Analyzer) throttle() error {
+	if a.ctx.Err() != nil {
+		return fmt.Errorf("操作已取消")
+	}
+	if limiter := a.pool.Limiter(); limiter != nil {
+		if err := limiter.Wait(a.ctx); err != nil {
+			return fmt.Errorf("操作已取消")
+		}
+	}
+	return nil
+}
+
+// fetchBatch 获取一批邮件的信封、大小和 INTERNALDATE 并累加
+// 按月统计使用 INTERNALDATE（与清理按日期搜索的依据一致），服务器未返回时使用信封日期
+func fetchBatch(client *imapclient.Client, folder string, uidSet imap.UIDSet, batch *stats) error {
+	fetchCmd := client.Fetch(uidSet, &imap.FetchOptions{
+		Envelope:     true,
+		RFC822Size:   true,
+		InternalDate: true,
+	})
+	for msg := fetchCmd.Next(); msg != nil; msg = fetchCmd.Next() {
+		var sender string
+		var date, internalDate time.Time
+		var size int64
+		for item := msg.Next(); item != nil; item = msg.Next() {
+			switch data := item.(type) {
+			case imapclient.FetchItemDataEnvelope:
+				if data.Envelope != nil {
+					date = data.Envelope.Date
+					if len(data.Envelope.From) > 0 {
+						sender = strings.ToLower(data.Envelope.From[0].Addr())
+					}
+				}
+			case imapclient.FetchItemDataRFC822Size:
+				size = data.Size
+			case imapclient.FetchItemDataInternalDate:
+				internalDate = data.Time
+			}
+		}
+		if !internalDate.IsZero() {
+			date = internalDate
+		}
+		batch.add(folder, sender, date, size)
+	}
+	return fetchCmd.Close()
+}
+
+// sortRows 按邮件数降序（相同时按大小降序）排序，limit 大于 0 时只保留前 limit 行
+func sortRows(rows map[string]*model.AnalysisRow, limit int) []model.AnalysisRow {
+	result := make([]model.AnalysisRow, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	slices.SortFunc(result, func(x, y model.AnalysisRow) int {
+		if c := cmp.Compare(y.Count, x.Count); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(y.Size, x.Size); c != 0 {
+			return c
+		}
+		return strings.Compare(x.Key, y.Key)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// sortRowsByKey 按统计键升序排序（用于月份）
+func sortRowsByKey(rows map[string]*model.AnalysisRow) []model.AnalysisRow {
+	result := make([]model.AnalysisRow, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	slices.SortFunc(result, func(x, y model.AnalysisRow) int {
+		return strings.Compare(x.Key, y.Key)
+	})
+	return result
+}
+
+// getConnection 从连接池获取连接（带重试）
+func (a *Analyzer) getConnection() (*imapClient.PooledConn, error) {
+	var lastErr error
+	for retry := 0; retry < maxRetries; retry++ {
+		if a.ctx.Err() != nil {
+			return nil, fmt.Errorf("操作已取消")
+		}
+
+		if conn, err := a.pool.Get(a.ctx); err == nil {
+			return conn, nil
+		} else {
+			lastErr = err
+		}
+
+		if retry < maxRetries-1 {
+			log.Printf("[DEBUG] 获取连接失败，%v 后重试 (%d/%d): %v", retryInterval, retry+1, maxRetries, lastErr)
+			time.Sleep(retryInterval)
+		}
+	}
+	return nil, fmt.Errorf("获取连接失败，已重试 %d 次: %w", maxRetries, lastErr)
+}
+
+// sendProgress 发送进度
+func (a *Analyzer) sendProgress(progress *model.AnalysisProgress) {
+	select {
+	case a.progressCh <- progress:
+	default:
+		// 通道满了就丢弃
+	}
+}
@@ -0,0 +1,284 @@
+package analyzer
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+
+	imapClient "CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/email/query"
+	"CleanMyEmail/internal/model"
+)
+
+// folderLargest 单个文件夹中最大的邮件
+type folderLargest struct {
+	folder      string
+	uidValidity uint32
+	messages    []*model.PreviewMessage
+}
+
+// uidSize 邮件 UID 和大小
+type uidSize struct {
+	uid  imap.UID
+	size int64
+}
+
+// LargestMessages 查询占用空间最大的 N 封邮件
+// 每个文件夹先获取所有邮件的 RFC822.SIZE 选出前 N 封，再获取这些邮件的信封，最后合并所有文件夹取前 N 封
+// 结束后可通过 PlanFolders 获取结果涉及的 UID，用于生成清理计划
+func (a *Analyzer) LargestMessages(req *model.LargestMessagesRequest) (*model.LargestMessagesResult, error) {
+	if err := a.start(req.AccountID); err != nil {
+		return nil, err
+	}
+	defer a.finish()
+
+	startTime := time.Now()
+	result := &model.LargestMessagesResult{
+		AccountID: req.AccountID,
+		Status:    "completed",
+	}
+
+	folders := req.Folders
+	if len(folders) == 0 {
+		var err error
+		if folders, err = a.selectableFolders(); err != nil {
+			return nil, err
+		}
+	}
+	limit := req.GetLimit()
+	concurrency := req.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	var found []*folderLargest
+	var errs []string
+	var wg sync.WaitGroup
+	var mergeMu sync.Mutex
+	sem := make(chan struct{}, concurrency)
+	for i, folder := range folders {
+		if a.ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(idx int, folder string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-a.ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			largest, err := a.largestInFolder(folder, limit, idx, len(folders), startTime)
+			mergeMu.Lock()
+			defer mergeMu.Unlock()
+			if err != nil {
+				if a.ctx.Err() == nil {
+					log.Printf("[WARN] [%s] 查询最大邮件失败: %v", folder, err)
+					errs = append(errs, fmt.Sprintf("%s: %v", folder, err))
+				}
+				return
+			}
+			found = append(found, largest)
+		}(i, folder)
+	}
+	wg.Wait()
+
+	switch {
+	case a.ctx.Err() != nil:
+		result.Status = "cancelled"
+	case len(errs) > 0:
+		result.Status = "failed"
+		result.Error = strings.Join(errs, "; ")
+	}
+
+	validity := make(map[string]uint32, len(found))
+	for _, f := range found {
+		validity[f.folder] = f.uidValidity
+		result.Messages = append(result.Messages, f.messages...)
+	}
+	slices.SortFunc(result.Messages, func(x, y *model.PreviewMessage) int {
+		if c := cmp.Compare(y.Size, x.Size); c != 0 {
+			return c
+		}
+		if c := strings.Compare(x.Folder, y.Folder); c != 0 {
+			return c
+		}
+		return cmp.Compare(x.UID, y.UID)
+	})
+	if len(result.Messages) > limit {
+		result.Messages = result.Messages[:limit]
+	}
+	for _, msg := range result.Messages {
+		result.TotalSize += msg.Size
+	}
+	result.ScannedCount = a.addScanned(0)
+	result.Duration = time.Since(startTime).Seconds()
+	a.setPlanFolders(result.Messages, validity)
+
+	a.sendProgress(&model.AnalysisProgress{
+		AccountID:    req.AccountID,
+		TotalFolders: len(folders),
+		ScannedCount: result.ScannedCount,
+		Status:       result.Status,
+		Message: fmt.Sprintf("查询完成，共扫描 %d 封邮件，最大的 %d 封共 %.1fM",
+			result.ScannedCount, len(result.Messages), float64(result.TotalSize)/(1024*1024)),
+		ElapsedSeconds: result.Duration,
+	})
+	return result, nil
+}
+
+// PlanFolders 获取 LargestMessages 结果中各文件夹的 UID（结束后调用），用于生成清理计划
+func (a *Analyzer) PlanFolders() []*model.CleanPlanFolder {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.planFolders
+}
+
+// setPlanFolders 按文件夹汇总结果中的 UID
+func (a *Analyzer) setPlanFolders(messages []*model.PreviewMessage, validity map[string]uint32) {
+	uids := make(map[string][]imap.UID)
+	var order []string
+	for _, msg := range messages {
+		if _, ok := uids[msg.Folder]; !ok {
+			order = append(order, msg.Folder)
+		}
+		uids[msg.Folder] = append(uids[msg.Folder], imap.UID(msg.UID))
+	}
+
+	planFolders := make([]*model.CleanPlanFolder, 0, len(order))
+	for _, folder := range order {
+		uidSet := imap.UIDSet{}
+		uidSet.AddNum(uids[folder]...)
+		planFolders = append(planFolders, &model.CleanPlanFolder{
+			Folder:      folder,
+			UIDValidity: validity[folder],
+			UIDs:        uidSet.String(),
+			Count:       len(uids[folder]),
+		})
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.planFolders = planFolders
+}
+
+// selectableFolders 获取账号所有可选择的文件夹
+func (a *Analyzer) selectableFolders() ([]string, error) {
+	conn, err := a.getConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	mailboxes, err := imapClient.ListMailboxes(conn.Client())
+	if err != nil {
+		conn.MarkBad()
+		return nil, err
+	}
+	var folders []string
+	for _, mbox := range mailboxes {
+		if mbox.IsSelectable {
+			folders = append(folders, mbox.FullPath)
+		}
+	}
+	return folders, nil
+}
+
+// largestInFolder 获取单个文件夹中最大的 limit 封邮件
+func (a *Analyzer) largestInFolder(folder string, limit, folderIdx, totalFolders int, startTime time.Time) (*folderLargest, error) {
+	conn, err := a.getConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if conn != nil {
+			conn.Release()
+		}
+	}()
+
+	selectData, err := conn.Client().Select(folder, &imap.SelectOptions{ReadOnly: true}).Wait()
+	if err != nil {
+		conn.MarkBad()
+		return nil, fmt.Errorf("选择文件夹失败: %w", err)
+	}
+	largest := &folderLargest{folder: folder, uidValidity: selectData.UIDValidity}
+	if selectData.NumMessages == 0 {
+		return largest, nil
+	}
+
+	// 获取所有邮件的大小（只有 UID 和 RFC822.SIZE，数据量很小）
+	var sizes []uidSize
+	conn, err = a.retryWithReconnect(conn, folder, func(cli *imapclient.Client) error {
+		sizes = sizes[:0]
+		seqSet := imap.SeqSet{}
+		seqSet.AddRange(1, 0) // 1:*
+		fetchCmd := cli.Fetch(seqSet, &imap.FetchOptions{UID: true, RFC822Size: true})
+		for msg := fetchCmd.Next(); msg != nil; msg = fetchCmd.Next() {
+			var entry uidSize
+			for item := msg.Next(); item != nil; item = msg.Next() {
+				switch data := item.(type) {
+				case imapclient.FetchItemDataUID:
+					entry.uid = data.UID
+				case imapclient.FetchItemDataRFC822Size:
+					entry.size = data.Size
+				}
+			}
+			if entry.uid != 0 {
+				sizes = append(sizes, entry)
+			}
+		}
+		return fetchCmd.Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a.sendProgress(&model.AnalysisProgress{
+		AccountID:      a.accountID,
+		CurrentFolder:  folder,
+		FolderIndex:    folderIdx + 1,
+		TotalFolders:   totalFolders,
+		ScannedCount:   a.addScanned(len(sizes)),
+		Status:         "running",
+		Message:        fmt.Sprintf("文件夹 %s: 已扫描 %d 封邮件", folder, len(sizes)),
+		ElapsedSeconds: time.Since(startTime).Seconds(),
+	})
+
+	slices.SortFunc(sizes, func(x, y uidSize) int {
+		return cmp.Compare(y.size, x.size)
+	})
+	if len(sizes) > limit {
+		sizes = sizes[:limit]
+	}
+	if len(sizes) == 0 {
+		return largest, nil
+	}
+
+	uidSet := imap.UIDSet{}
+	for _, entry := range sizes {
+		uidSet.AddNum(entry.uid)
+	}
+	conn, err = a.retryWithReconnect(conn, folder, func(cli *imapclient.Client) error {
+		largest.messages = largest.messages[:0]
+		fetchCmd := cli.Fetch(uidSet, query.FetchOptions())
+		for msg := fetchCmd.Next(); msg != nil; msg = fetchCmd.Next() {
+			if preview := imapClient.CollectPreviewMessage(msg, folder); preview != nil {
+				largest.messages = append(largest.messages, preview)
+			}
+		}
+		return fetchCmd.Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return largest, nil
+}
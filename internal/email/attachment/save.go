@@ -0,0 +1,62 @@
+package attachment
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"CleanMyEmail/internal/config"
+)
+
+// NewRunDir 生成一次移除附件任务的保存目录路径
+func NewRunDir(accountID int64) string {
+	name := fmt.Sprintf("%s-account%d", time.Now().Format("20060102-150405"), accountID)
+	return filepath.Join(config.GetDataDir(), "attachments", name)
+}
+
+// FileSaver 返回将附件保存到 dir/<文件夹>/<UID>-<文件名> 的 SaveFunc
+// 同一封邮件中的重名附件会加序号，重试时覆盖之前保存的文件
+func FileSaver(dir, folder string, uid uint32) SaveFunc {
+	used := make(map[string]bool)
+	return func(filename, contentType string, data []byte) (string, error) {
+		folderDir := filepath.Join(dir, sanitizeFileName(folder, "folder"))
+		if err := os.MkdirAll(folderDir, 0755); err != nil {
+			return "", err
+		}
+
+		name := sanitizeFileName(filename, "")
+		if name == "" {
+			name = "attachment"
+			if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+				name += exts[0]
+			}
+		}
+		base := fmt.Sprintf("%d-%s", uid, name)
+		fileName := base
+		for i := 2; used[fileName]; i++ {
+			ext := filepath.Ext(base)
+			fileName = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), i, ext)
+		}
+		used[fileName] = true
+
+		path := filepath.Join(folderDir, fileName)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+}
+
+// sanitizeFileName 将文件夹名或附件名转换为安全的文件名
+func sanitizeFileName(name, fallback string) string {
+	replacer := strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_", "?", "_",
+		"\"", "_", "<", "_", ">", "_", "|", "_")
+	name = strings.Trim(strings.TrimSpace(replacer.Replace(name)), ".")
+	if name == "" {
+		return fallback
+	}
+	return name
+}
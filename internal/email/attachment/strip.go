@@ -0,0 +1,252 @@
+package attachment
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+)
+
+const maxDepth = 10 // 最大 multipart 嵌套层数，超过后不再处理
+
+// Part 从邮件中移除的附件
+type Part struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"` // 解码后的大小
+	SavedPath   string `json:"savedPath"`
+}
+
+// SaveFunc 保存附件内容，返回保存路径
+type SaveFunc func(filename, contentType string, data []byte) (string, error)
+
+// Strip 移除邮件中的附件：每个附件先通过 save 保存，再替换为一段说明文字
+// 只处理 multipart 邮件中编码后不小于 minSize 字节的附件，正文和其余部分保持原样
+// 没有可移除的附件时返回 nil
+func Strip(raw []byte, minSize int64, save SaveFunc) ([]byte, []Part, error) {
+	header, body := splitHeader(raw)
+	var parts []Part
+	newBody, changed, err := stripEntity(header, body, minSize, save, &parts, 0)
+	if err != nil || !changed {
+		return nil, nil, err
+	}
+	rebuilt := make([]byte, 0, len(header)+len(newBody))
+	rebuilt = append(rebuilt, header...)
+	rebuilt = append(rebuilt, newBody...)
+	return rebuilt, parts, nil
+}
+
+// stripEntity 处理一个 MIME 实体，返回替换附件后的正文
+func stripEntity(header, body []byte, minSize int64, save SaveFunc, parts *[]Part, depth int) ([]byte, bool, error) {
+	mediaType, params, _ := mime.ParseMediaType(parseHeader(header).Get("Content-Type"))
+	boundary := params["boundary"]
+	if !strings.HasPrefix(mediaType, "multipart/") || boundary == "" || depth >= maxDepth {
+		return body, false, nil
+	}
+
+	pieces, ok := splitMultipart(body, boundary)
+	if !ok {
+		return body, false, nil
+	}
+
+	changed := false
+	for i, p := range pieces {
+		if !p.isPart {
+			continue
+		}
+		partHeader, partBody := splitHeader(p.data)
+		h := parseHeader(partHeader)
+		if isAttachment(h) && int64(len(partBody)) >= minSize {
+			part, placeholder, err := replaceAttachment(h, partBody, save)
+			if err != nil {
+				return nil, false, err
+			}
+			*parts = append(*parts, *part)
+			pieces[i].data = placeholder
+			changed = true
+			continue
+		}
+
+		newBody, partChanged, err := stripEntity(partHeader, partBody, minSize, save, parts, depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+		if partChanged {
+			pieces[i].data = append(append([]byte{}, partHeader...), newBody...)
+			changed = true
+		}
+	}
+	if !changed {
+		return body, false, nil
+	}
+
+	var buf bytes.Buffer
+	for _, p := range pieces {
+		buf.Write(p.data)
+	}
+	return buf.Bytes(), true, nil
+}
+
+// isAttachment 是否为附件：Content-Disposition 为 attachment，或带有文件名的非文本部分
+func isAttachment(h textproto.MIMEHeader) bool {
+	mediaType, params, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	if strings.HasPrefix(mediaType, "multipart/") {
+		return false
+	}
+	disposition, dispParams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	if disposition == "attachment" {
+		return true
+	}
+	hasName := dispParams["filename"] != "" || params["name"] != ""
+	return hasName && !strings.HasPrefix(mediaType, "text/")
+}
+
+// replaceAttachment 保存附件并生成替代的说明部分
+func replaceAttachment(h textproto.MIMEHeader, body []byte, save SaveFunc) (*Part, []byte, error) {
+	mediaType, params, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	_, dispParams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := dispParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+
+	data := decodeBody(h.Get("Content-Transfer-Encoding"), body)
+	path, err := save(filename, mediaType, data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("保存附件 %s 失败: %w", filename, err)
+	}
+
+	part := &Part{
+		Filename:    filename,
+		ContentType: mediaType,
+		Size:        int64(len(data)),
+		SavedPath:   path,
+	}
+	return part, placeholderPart(part), nil
+}
+
+// decodeBody 按 Content-Transfer-Encoding 解码，解码失败时返回原始内容
+func decodeBody(encoding string, body []byte) []byte {
+	var r io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, bytes.NewReader(body))
+	case "quoted-printable":
+		r = quotedprintable.NewReader(bytes.NewReader(body))
+	default:
+		return body
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return body
+	}
+	return data
+}
+
+// placeholderPart 生成替代附件的 text/plain 部分（base64 编码，避免服务器拒收 8bit 内容）
+func placeholderPart(part *Part) []byte {
+	name := part.Filename
+	if name == "" {
+		name = "(未命名)"
+	}
+	text := fmt.Sprintf("附件「%s」（%s，%d 字节）已从邮件中移除，保存在本地：%s\r\n",
+		name, part.ContentType, part.Size, part.SavedPath)
+
+	var buf bytes.Buffer
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("Content-Disposition: inline\r\n")
+	buf.WriteString("X-Stripped-Attachment: " + mime.BEncoding.Encode("utf-8", name) + "\r\n")
+	buf.WriteString("\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(text))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded)
+	return buf.Bytes()
+}
+
+// splitHeader 拆分邮件头和正文，邮件头包含结尾的空行
+func splitHeader(raw []byte) ([]byte, []byte) {
+	crlf := bytes.Index(raw, []byte("\r\n\r\n"))
+	lf := bytes.Index(raw, []byte("\n\n"))
+	switch {
+	case crlf >= 0 && (lf < 0 || crlf < lf):
+		return raw[:crlf+4], raw[crlf+4:]
+	case lf >= 0:
+		return raw[:lf+2], raw[lf+2:]
+	default:
+		return raw, nil
+	}
+}
+
+// parseHeader 解析邮件头（只用于读取字段，重建时仍使用原始字节）
+func parseHeader(header []byte) textproto.MIMEHeader {
+	r := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(header), strings.NewReader("\r\n\r\n"))))
+	h, _ := r.ReadMIMEHeader()
+	if h == nil {
+		h = textproto.MIMEHeader{}
+	}
+	return h
+}
+
+// piece multipart 正文中的一段：子部分，或子部分之间的分隔符、前言和结语
+type piece struct {
+	data   []byte
+	isPart bool
+}
+
+// splitMultipart 按边界拆分 multipart 正文，拼接所有片段可得到原文
+// 分隔行之前的换行属于分隔符；缺少结束边界时返回 false，不做处理
+func splitMultipart(body []byte, boundary string) ([]piece, bool) {
+	delim := []byte("--" + boundary)
+	var pieces []piece
+	pos := 0        // 尚未归入任何片段的起始位置
+	partStart := -1 // 当前子部分的起始位置，第一个边界之前为 -1
+
+	for lineStart := 0; lineStart < len(body); {
+		lineEnd := len(body)
+		if i := bytes.IndexByte(body[lineStart:], '\n'); i >= 0 {
+			lineEnd = lineStart + i + 1
+		}
+		line := bytes.TrimRight(body[lineStart:lineEnd], " \t\r\n")
+		if bytes.HasPrefix(line, delim) {
+			rest := line[len(delim):]
+			isClose := bytes.Equal(rest, []byte("--"))
+			if len(rest) == 0 || isClose {
+				if partStart >= 0 {
+					end := lineStart
+					if end > partStart && body[end-1] == '\n' {
+						end--
+						if end > partStart && body[end-1] == '\r' {
+							end--
+						}
+					}
+					pieces = append(pieces, piece{data: body[partStart:end], isPart: true})
+					pos = end
+				}
+				pieces = append(pieces, piece{data: body[pos:lineEnd]})
+				pos = lineEnd
+				if isClose {
+					pieces = append(pieces, piece{data: body[pos:]})
+					return pieces, true
+				}
+				partStart = lineEnd
+			}
+		}
+		lineStart = lineEnd
+	}
+	return nil, false
+}
@@ -0,0 +1,120 @@
+package cleaner
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+
+	imapClient "CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/model"
+)
+
+// AuditStore 删除审计记录存储
+type AuditStore interface {
+	SaveDeletedMessages(messages []*model.DeletedMessage) error
+	UpdateDeletedMessagesStatus(ids []int64, status string, deletedAt time.Time) error
+}
+
+// EnableAudit 启用删除审计：每批删除前获取邮件的 Message-ID、收发件人、主题、日期和大小，
+// 先写入待确认的审计记录再删除，删除结束后更新记录状态，关联到 historyID 对应的清理历史
+func (c *Cleaner) EnableAudit(historyID int64, accountEmail string, store AuditStore) {
+	c.historyID = historyID
+	c.auditAccount = accountEmail
+	c.auditStore = store
+}
+
+// fetchDeletedInfo 删除前获取一批邮件的原始大小合计；启用删除审计时同时获取信封，生成审计记录
+func (c *Cleaner) fetchDeletedInfo(client *imapclient.Client, ctx *cleanFolderContext, uids []imap.UID) (int64, []*model.DeletedMessage, error) {
+	if len(uids) == 0 {
+		return 0, nil, nil
+	}
+	if err := c.throttleFor(ctx); err != nil {
+		return 0, nil, err
+	}
+
+	options := &imap.FetchOptions{RFC822Size: true}
+	if c.auditStore != nil {
+		options.UID = true
+		options.Envelope = true
+	}
+
+	var size int64
+	var records []*model.DeletedMessage
+	fetchCmd := client.Fetch(imap.UIDSetNum(uids...), options)
+	for msg := fetchCmd.Next(); msg != nil; msg = fetchCmd.Next() {
+		record := &model.DeletedMessage{Folder: ctx.folderName}
+		for item := msg.Next(); item != nil; item = msg.Next() {
+			switch data := item.(type) {
+			case imapclient.FetchItemDataUID:
+				record.UID = uint32(data.UID)
+			case imapclient.FetchItemDataRFC822Size:
+				record.Size = data.Size
+			case imapclient.FetchItemDataEnvelope:
+				if data.Envelope == nil {
+					continue
+				}
+				record.MessageID = data.Envelope.MessageID
+				record.Subject = data.Envelope.Subject
+				record.Date = data.Envelope.Date
+				if len(data.Envelope.From) > 0 {
+					record.From = imapClient.FormatAddress(data.Envelope.From[0])
+				}
+				to := make([]string, len(data.Envelope.To))
+				for i, addr := range data.Envelope.To {
+					to[i] = imapClient.FormatAddress(addr)
+				}
+				record.To = strings.Join(to, ", ")
+			}
+		}
+		size += record.Size
+		if c.auditStore != nil && record.UID != 0 {
+			records = append(records, record)
+		}
+	}
+	if err := fetchCmd.Close(); err != nil {
+		return 0, nil, fmt.Errorf("获取邮件信息失败: %w", err)
+	}
+	return size, records, nil
+}
+
+// recordAudit 删除前写入待确认的审计记录，写入失败时返回错误，调用方不得继续删除
+func (c *Cleaner) recordAudit(ctx *cleanFolderContext, records []*model.DeletedMessage) error {
+	if c.auditStore == nil || len(records) == 0 {
+		return nil
+	}
+	action := string(ctx.deleteMode)
+	if ctx.action == model.CleanActionStripAttachments {
+		action = string(ctx.action)
+	}
+	now := time.Now()
+	for _, record := range records {
+		record.HistoryID = c.historyID
+		record.AccountID = ctx.req.AccountID
+		record.AccountEmail = c.auditAccount
+		record.Action = action
+		record.Status = model.DeletedMessagePending
+		record.DeletedAt = now
+	}
+	if err := c.auditStore.SaveDeletedMessages(records); err != nil {
+		return fmt.Errorf("写入删除审计记录失败: %w", err)
+	}
+	return nil
+}
+
+// finishAudit 删除结束后更新审计记录的状态，更新失败时记录保持 pending，只记录日志
+func (c *Cleaner) finishAudit(ctx *cleanFolderContext, records []*model.DeletedMessage, status string) {
+	if c.auditStore == nil || len(records) == 0 {
+		return
+	}
+	ids := make([]int64, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	if err := c.auditStore.UpdateDeletedMessagesStatus(ids, status, time.Now()); err != nil {
+		log.Printf("[WARN] [%s] 更新删除审计记录状态失败（%d 封）: %v", ctx.folderName, len(records), err)
+	}
+}
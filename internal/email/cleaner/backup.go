@@ -0,0 +1,81 @@
+package cleaner
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+
+	"CleanMyEmail/internal/archive"
+)
+
+// archiveBatch 将一批邮件的完整原文归档到本地
+// 返回已安全落盘、可以删除的 UID；未能归档的邮件不会出现在返回值中
+func (c *Cleaner) archiveBatch(client *imapclient.Client, ctx *cleanFolderContext, uids []imap.UID) ([]imap.UID, error) {
+	uidSet := imap.UIDSet{}
+	for _, uid := range uids {
+		if !c.archiver.IsArchived(ctx.folderName, uint32(uid)) {
+			uidSet.AddNum(uid)
+		}
+	}
+
+	if len(uidSet) > 0 {
+		if err := c.throttle(); err != nil {
+			return nil, err
+		}
+		bodySection := &imap.FetchItemBodySection{Peek: true}
+		fetchCmd := client.Fetch(uidSet, &imap.FetchOptions{
+			UID:          true,
+			Flags:        true,
+			InternalDate: true,
+			Envelope:     true,
+			BodySection:  []*imap.FetchItemBodySection{bodySection},
+		})
+		for msg := fetchCmd.Next(); msg != nil; msg = fetchCmd.Next() {
+			buf, err := msg.Collect()
+			if err != nil {
+				fetchCmd.Close()
+				return nil, fmt.Errorf("获取邮件原文失败: %w", err)
+			}
+			body := buf.FindBodySection(bodySection)
+			if buf.UID == 0 || body == nil {
+				continue
+			}
+
+			archived := &archive.Message{
+				UID:          uint32(buf.UID),
+				Flags:        make([]string, 0, len(buf.Flags)),
+				InternalDate: buf.InternalDate,
+				Body:         body,
+			}
+			for _, flag := range buf.Flags {
+				archived.Flags = append(archived.Flags, string(flag))
+			}
+			if buf.Envelope != nil {
+				archived.MessageID = buf.Envelope.MessageID
+			}
+			if err := c.archiver.Write(ctx.folderName, archived); err != nil {
+				fetchCmd.Close()
+				return nil, err
+			}
+		}
+		if err := fetchCmd.Close(); err != nil {
+			return nil, fmt.Errorf("获取邮件原文失败: %w", err)
+		}
+		if err := c.archiver.Sync(); err != nil {
+			return nil, err
+		}
+	}
+
+	safeUIDs := make([]imap.UID, 0, len(uids))
+	for _, uid := range uids {
+		if c.archiver.IsArchived(ctx.folderName, uint32(uid)) {
+			safeUIDs = append(safeUIDs, uid)
+		}
+	}
+	if skipped := len(uids) - len(safeUIDs); skipped > 0 {
+		log.Printf("[WARN] [%s] %d 封邮件未能归档，跳过删除", ctx.folderName, skipped)
+	}
+	return safeUIDs, nil
+}
@@ -0,0 +1,93 @@
+package cleaner
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	imapClient "CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/model"
+)
+
+const (
+	adaptiveStartBatch    = 50               // 自适应模式的初始批大小
+	adaptiveMinBatch      = 10               // 自适应模式的最小批大小
+	adaptiveMaxBatch      = 2000             // 未设置批大小时自适应模式的上限
+	adaptiveMaxFetchBatch = 500              // 自适应模式获取邮件头的批大小上限
+	healthyLatency        = 2 * time.Second  // 低于该耗时时增大批大小
+	slowLatency           = 10 * time.Second // 超过该耗时时减小批大小
+)
+
+// batchSizer 批大小控制：固定模式始终返回同一大小；自适应模式从较小的批开始，
+// 往返耗时正常时逐步增大，变慢时减半，超时或服务器以 BAD/NO 拒绝时降为四分之一
+type batchSizer struct {
+	mu       sync.Mutex
+	adaptive bool
+	current  int
+	min      int
+	max      int
+}
+
+// newBatchSizer 创建批大小控制，adaptive 为 false 时固定为 size，否则在 limit 以内自动调整
+func newBatchSizer(size int, adaptive bool, limit int) *batchSizer {
+	if !adaptive {
+		return &batchSizer{current: size, min: size, max: size}
+	}
+	return &batchSizer{adaptive: true, current: min(adaptiveStartBatch, limit), min: min(adaptiveMinBatch, limit), max: limit}
+}
+
+// size 当前批大小
+func (b *batchSizer) size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current
+}
+
+// observe 记录一次批量命令的耗时和结果并调整批大小，返回批大小是否减小
+func (b *batchSizer) observe(elapsed time.Duration, err error) bool {
+	if !b.adaptive {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	prev := b.current
+	switch {
+	case err == nil && elapsed < healthyLatency:
+		b.current = min(b.current+max(b.current/2, adaptiveMinBatch), b.max)
+	case err == nil && elapsed > slowLatency:
+		b.current = max(b.current/2, b.min)
+	case err != nil && !imapClient.IsThrottled(err) && (isCommandRejected(err) || isTimeout(err)):
+		// 限流由限速器处理，不影响批大小
+		b.current = max(b.current/4, b.min)
+	}
+	return b.current < prev
+}
+
+// isTimeout 是否为超时或命令执行中连接被服务器断开（常见于过大的批量命令）
+func isTimeout(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// adaptiveBatchLimit 自适应模式下删除批大小的上限：请求设置了批大小时以其为上限
+func adaptiveBatchLimit(req *model.CleanRequest) int {
+	if req.BatchSize > 0 {
+		return req.BatchSize
+	}
+	return adaptiveMaxBatch
+}
+
+// throttleFor 等待限速并把等待时间计入 ctx.throttleWait，用于从批量命令的耗时中扣除
+func (c *Cleaner) throttleFor(ctx *cleanFolderContext) error {
+	started := time.Now()
+	err := c.throttle()
+	ctx.throttleWait += time.Since(started)
+	return err
+}
@@ -0,0 +1,132 @@
+package cleaner
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+
+	"CleanMyEmail/internal/model"
+)
+
+// CheckpointStore 清理检查点存储，用于应用中断后继续清理
+type CheckpointStore interface {
+	LoadCheckpoints(historyID int64) (map[string]*model.CleanCheckpoint, error)
+	SaveCheckpoint(checkpoint *model.CleanCheckpoint) error
+}
+
+// EnableCheckpoint 启用检查点：删除过程中持久化每个文件夹的进度
+// 如果该历史记录已有检查点（继续中断的任务），会从检查点位置继续
+func (c *Cleaner) EnableCheckpoint(historyID int64, store CheckpointStore) {
+	c.historyID = historyID
+	c.checkpointStore = store
+}
+
+// loadCheckpoints 加载已有的检查点
+func (c *Cleaner) loadCheckpoints() {
+	c.checkpoints = nil
+	if c.checkpointStore == nil {
+		return
+	}
+	checkpoints, err := c.checkpointStore.LoadCheckpoints(c.historyID)
+	if err != nil {
+		log.Printf("[WARN] 加载清理检查点失败: %v", err)
+		return
+	}
+	if len(checkpoints) > 0 {
+		log.Printf("[DEBUG] 从检查点继续清理，共 %d 个文件夹有进度记录", len(checkpoints))
+	}
+	c.checkpoints = checkpoints
+}
+
+// saveCheckpoint 保存文件夹的删除进度
+func (c *Cleaner) saveCheckpoint(ctx *cleanFolderContext, stat *model.FolderCleanStat, remaining []imap.UID, status string) {
+	if c.checkpointStore == nil {
+		return
+	}
+	ctx.checkpoint = &model.CleanCheckpoint{
+		HistoryID:     c.historyID,
+		Folder:        ctx.folderName,
+		UIDValidity:   ctx.uidValidity,
+		RemainingUIDs: formatUIDs(remaining),
+		MatchedCount:  stat.MatchedCount,
+		DeletedCount:  stat.DeletedCount,
+		Status:        status,
+	}
+	c.storeCheckpoint(ctx)
+}
+
+// storeCheckpoint 写入文件夹最近一次的检查点，附带已上传去附件副本、原邮件尚未删除的 UID
+// 移除附件时每上传一封都会调用，应用在删除原邮件前退出时，继续清理不会再次上传同一封邮件
+func (c *Cleaner) storeCheckpoint(ctx *cleanFolderContext) {
+	if c.checkpointStore == nil || ctx.checkpoint == nil {
+		return
+	}
+	var stripped []imap.UID
+	for uid, saved := range ctx.stripped {
+		if saved >= 0 {
+			stripped = append(stripped, uid)
+		}
+	}
+	slices.Sort(stripped)
+	ctx.checkpoint.StrippedUIDs = formatUIDs(stripped)
+	if err := c.checkpointStore.SaveCheckpoint(ctx.checkpoint); err != nil {
+		log.Printf("[WARN] [%s] 保存清理检查点失败: %v", ctx.folderName, err)
+	}
+}
+
+// formatUIDs 将 UID 列表格式化为 IMAP UID 集合字符串
+func formatUIDs(uids []imap.UID) string {
+	if len(uids) == 0 {
+		return ""
+	}
+	uidSet := imap.UIDSet{}
+	uidSet.AddNum(uids...)
+	return uidSet.String()
+}
+
+// parseUIDs 解析 IMAP UID 集合字符串（如 "1:5,8"）
+func parseUIDs(s string) ([]imap.UID, error) {
+	var uids []imap.UID
+	if s == "" {
+		return uids, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		start, stop, isRange := strings.Cut(part, ":")
+		from, err := strconv.ParseUint(start, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 UID 集合 %q: %w", s, err)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.ParseUint(stop, 10, 32); err != nil {
+				return nil, fmt.Errorf("无效的 UID 集合 %q: %w", s, err)
+			}
+		}
+		if from > to {
+			from, to = to, from
+		}
+		for uid := from; uid <= to; uid++ {
+			uids = append(uids, imap.UID(uid))
+		}
+	}
+	return uids, nil
+}
+
+// intersectUIDs 返回同时出现在 uids 和 allowed 中的 UID，保持 uids 的顺序
+func intersectUIDs(uids, allowed []imap.UID) []imap.UID {
+	set := make(map[imap.UID]struct{}, len(allowed))
+	for _, uid := range allowed {
+		set[uid] = struct{}{}
+	}
+	result := make([]imap.UID, 0, min(len(uids), len(allowed)))
+	for _, uid := range uids {
+		if _, ok := set[uid]; ok {
+			result = append(result, uid)
+		}
+	}
+	return result
+}
@@ -4,11 +4,18 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/emersion/go-imap/v2"
+
+	"CleanMyEmail/internal/archive"
+	"CleanMyEmail/internal/db"
+	"CleanMyEmail/internal/email/attachment"
 	imapClient "CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/email/query"
 	"CleanMyEmail/internal/model"
 )
 
@@ -24,8 +31,39 @@ type Cleaner struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	progressCh chan *model.CleanProgress
+	progress   *progressAggregator // 本次清理的进度聚合器，Clean 开始时创建
 	mu         sync.Mutex
 	running    bool
+	used       bool          // 已执行过 Clean：进度通道已关闭（自建的连接池也已关闭），清理器不能重复使用
+	resumeCh   chan struct{} // 暂停时不为 nil，继续时关闭，受 mu 保护
+	archiveDir string          // 删除前备份的归档目录（为空时自动生成）
+	archiver   *archive.Writer // 本次清理的归档写入器，未启用备份时为 nil
+
+	attachmentDir string // 移除附件时附件的保存目录（为空时自动生成）
+
+	// 筛选表达式（FilterQuery），相对时间以本次清理开始时间为基准
+	filterQuery query.Node
+	queryTime   time.Time
+
+	// 预览模式下符合条件的邮件明细和 UID 集合，受 mu 保护
+	previewMessages []*model.PreviewMessage
+	planFolders     []*model.CleanPlanFolder
+
+	// 受保护的发件人，每次清理开始时从设置中加载
+	protected *model.ProtectedSenders
+
+	// 按清理计划执行时计划中各文件夹的 UID，key: 文件夹
+	plan          map[string]*model.CleanPlanFolder
+	planExpiresAt time.Time
+
+	// 检查点（中断后继续）
+	historyID       int64
+	checkpointStore CheckpointStore
+	checkpoints     map[string]*model.CleanCheckpoint // 上次运行保存的检查点，按文件夹索引
+
+	// 删除审计，未启用时 auditStore 为 nil
+	auditStore   AuditStore
+	auditAccount string // 审计记录中的账号邮箱
 }
 
 // NewCleaner 创建清理器（使用外部连接池）
@@ -46,6 +84,7 @@ func NewCleanerWithConfig(config *imapClient.ConnectConfig, concurrency int) *Cl
 		MaxSize:     concurrency,
 		IdleTimeout: 5 * time.Minute,
 	})
+	pool.SetLimiter(imapClient.NewRateLimiter(config.Server, config.RateLimit))
 	return &Cleaner{
 		pool:       pool,
 		ownsPool:   true,
@@ -53,11 +92,36 @@ func NewCleanerWithConfig(config *imapClient.ConnectConfig, concurrency int) *Cl
 	}
 }
 
-// ProgressChan 获取进度通道
+// ProgressChan 获取进度通道，调用方须持续读取直到通道关闭（Clean 结束时关闭）
+// 每个清理器只能执行一次 Clean，再次清理需要创建新的清理器
 func (c *Cleaner) ProgressChan() <-chan *model.CleanProgress {
 	return c.progressCh
 }
 
+// SetArchiveDir 设置删除前备份的归档目录
+func (c *Cleaner) SetArchiveDir(dir string) {
+	c.archiveDir = dir
+}
+
+// SetAttachmentDir 设置移除附件时附件的保存目录
+func (c *Cleaner) SetAttachmentDir(dir string) {
+	c.attachmentDir = dir
+}
+
+// PreviewMessages 获取预览模式下符合条件的邮件明细（Clean 结束后调用）
+func (c *Cleaner) PreviewMessages() []*model.PreviewMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.previewMessages
+}
+
+// addPreviewMessages 追加预览邮件明细（各文件夹并发调用）
+func (c *Cleaner) addPreviewMessages(messages []*model.PreviewMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.previewMessages = append(c.previewMessages, messages...)
+}
+
 // Clean 执行清理
 func (c *Cleaner) Clean(req *model.CleanRequest) (*model.CleanResult, error) {
 	c.mu.Lock()
@@ -65,11 +129,19 @@ func (c *Cleaner) Clean(req *model.CleanRequest) (*model.CleanResult, error) {
 		c.mu.Unlock()
 		return nil, fmt.Errorf("清理任务正在进行中")
 	}
+	if c.used {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("清理器已执行过清理，不能重复使用")
+	}
 	c.running = true
+	c.used = true
 	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.previewMessages = nil
+	c.planFolders = nil
 	c.mu.Unlock()
 
 	startTime := time.Now()
+	c.progress = newProgressAggregator(c.ctx, c.progressCh, req, startTime)
 	result := &model.CleanResult{
 		AccountID:   req.AccountID,
 		FolderStats: make([]model.FolderCleanStat, 0, len(req.Folders)),
@@ -90,24 +162,129 @@ func (c *Cleaner) Clean(req *model.CleanRequest) (*model.CleanResult, error) {
 		c.mu.Lock()
 		c.running = false
 		c.mu.Unlock()
-		close(c.progressCh)
+		c.progress.finish(nil)
 	}()
 
-	// 解析日期
+	// 只能按计划执行的请求不含筛选条件，没有计划时重新搜索会匹配文件夹中的所有邮件
+	if req.PlanOnly && c.plan == nil {
+		return nil, fmt.Errorf("该清理请求只能按清理计划执行")
+	}
+
+	// 相对日期：调用方未预先解析时按用户时区解析（预先解析可以把解析结果记录到历史）
+	if req.HasRelativeDate() && req.EndDate == "" {
+		if err := req.ResolveDates(startTime.In(db.GetLocation())); err != nil {
+			return nil, err
+		}
+	}
+
+	switch req.GetDateBasis() {
+	case model.DateBasisInternal, model.DateBasisSent:
+	default:
+		return nil, fmt.Errorf("不支持的日期依据: %s", req.DateBasis)
+	}
+
+	// 解析日期：按用户时区的日期处理，IMAP 日期条件只比较日期部分
+	loc := db.GetLocation()
 	var startDate time.Time
 	var err error
 	if req.StartDate != "" {
-		startDate, err = time.Parse("2006-01-02", req.StartDate)
+		startDate, err = time.ParseInLocation("2006-01-02", req.StartDate, loc)
 		if err != nil {
 			return nil, fmt.Errorf("开始日期格式错误: %w", err)
 		}
 	}
-	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	var endDate time.Time
+	if req.EndDate != "" {
+		endDate, err = time.ParseInLocation("2006-01-02", req.EndDate, loc)
+		if err != nil {
+			return nil, fmt.Errorf("结束日期格式错误: %w", err)
+		}
+		// 结束日期加一天（包含当天），之后作为 BEFORE 的日期使用
+		endDate = endDate.AddDate(0, 0, 1)
+	} else if req.FilterQuery == "" {
+		return nil, fmt.Errorf("请设置结束日期或筛选表达式")
+	}
+
+	// 解析筛选表达式
+	c.filterQuery, err = query.Parse(req.FilterQuery, loc)
 	if err != nil {
-		return nil, fmt.Errorf("结束日期格式错误: %w", err)
+		return nil, fmt.Errorf("筛选表达式错误: %w", err)
+	}
+	c.queryTime = startTime.In(loc)
+	if c.filterQuery != nil {
+		log.Printf("[DEBUG] 筛选表达式: %s", c.filterQuery)
+	}
+
+	if req.Exclusions != nil {
+		req.Exclusions.Normalize()
+	}
+
+	// 受保护的发件人对所有清理（含预览、定时清理和命令行）生效，读取失败时不执行清理
+	c.protected, err = db.GetProtectedSenders()
+	if err != nil {
+		return nil, fmt.Errorf("读取受保护发件人失败: %w", err)
+	}
+	c.protected.Normalize()
+
+	// 按清理计划执行前校验 UIDVALIDITY 和有效期
+	if c.plan != nil && !req.PreviewOnly {
+		if err := c.verifyPlan(); err != nil {
+			return nil, err
+		}
+	}
+
+	// 解析移动目标文件夹（预览模式不需要）
+	var targetFolder string
+	if !req.PreviewOnly && req.GetDeleteMode().IsMove() {
+		if targetFolder, err = c.resolveTargetFolder(req); err != nil {
+			return nil, err
+		}
+		log.Printf("[DEBUG] 删除方式: %s, 目标文件夹: %s", req.GetDeleteMode(), targetFolder)
+	}
+
+	// 加载检查点（继续中断的任务）
+	if !req.PreviewOnly {
+		c.loadCheckpoints()
+	}
+
+	// 删除前备份
+	if req.BackupBeforeDelete && !req.PreviewOnly {
+		dir := c.archiveDir
+		if dir == "" {
+			dir = archive.NewRunDir(req.AccountID)
+		}
+		writer, err := archive.NewWriter(dir)
+		if err != nil {
+			return nil, err
+		}
+		c.archiver = writer
+		result.ArchivePath = dir
+		defer func() {
+			if err := writer.Close(); err != nil {
+				log.Printf("[WARN] 关闭归档文件失败: %v", err)
+			}
+			c.archiver = nil
+		}()
+		log.Printf("[DEBUG] 删除前备份到: %s", dir)
+	}
+
+	// 移除附件的保存目录
+	switch req.GetAction() {
+	case model.CleanActionDelete:
+	case model.CleanActionStripAttachments:
+		if !req.PreviewOnly {
+			if c.attachmentDir == "" {
+				c.attachmentDir = attachment.NewRunDir(req.AccountID)
+			}
+			if err := os.MkdirAll(c.attachmentDir, 0755); err != nil {
+				return nil, fmt.Errorf("创建附件目录失败: %w", err)
+			}
+			result.AttachmentDir = c.attachmentDir
+			log.Printf("[DEBUG] 移除附件，保存到: %s", c.attachmentDir)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的清理动作: %s", req.Action)
 	}
-	// 结束日期加一天（包含当天）
-	endDate = endDate.Add(24 * time.Hour)
 
 	var totalDeleted int64
 	var wg sync.WaitGroup
@@ -130,25 +307,31 @@ func (c *Cleaner) Clean(req *model.CleanRequest) (*model.CleanResult, error) {
 			defer wg.Done()
 			defer func() { <-sem }()
 
-			stat := c.cleanFolder(folderName, startDate, endDate, req, idx, len(req.Folders), bs)
+			stat := c.cleanFolder(folderName, startDate, endDate, req, idx, len(req.Folders), bs, targetFolder)
+			c.progress.finishFolder(stat)
 			atomic.AddInt64(&totalDeleted, int64(stat.DeletedCount))
 			statsCh <- stat
 		}(i, folder, batchSize)
 	}
 
 	wg.Wait()
+	if c.ctx.Err() != nil {
+		result.Status = "cancelled"
+	}
 
 done:
 	close(statsCh)
 	for stat := range statsCh {
 		result.FolderStats = append(result.FolderStats, stat)
+		result.ReclaimedBytes += stat.ReclaimedBytes
+		result.ProcessedBytes += stat.ProcessedBytes
 	}
 
 	result.TotalDeleted = int(totalDeleted)
 	result.Duration = time.Since(startTime).Seconds()
 
-	// 发送完成进度
-	c.sendProgress(&model.CleanProgress{
+	// 发送完成进度并关闭进度通道
+	c.progress.finish(&model.CleanProgress{
 		AccountID:      req.AccountID,
 		Status:         result.Status,
 		DeletedCount:   result.TotalDeleted,
@@ -159,6 +342,37 @@ done:
 	return result, nil
 }
 
+// resolveTargetFolder 解析移动类删除的目标文件夹
+// trash 模式通过 \Trash 特殊用途属性查找废纸篓，folder 模式使用请求中指定的文件夹
+func (c *Cleaner) resolveTargetFolder(req *model.CleanRequest) (string, error) {
+	switch req.GetDeleteMode() {
+	case model.DeleteModeFolder:
+		if req.TargetFolder == "" {
+			return "", fmt.Errorf("请指定目标文件夹")
+		}
+		return req.TargetFolder, nil
+	case model.DeleteModeTrash:
+		conn, err := c.getConnection()
+		if err != nil {
+			return "", err
+		}
+		defer conn.Release()
+
+		folders, err := imapClient.ListMailboxes(conn.Client())
+		if err != nil {
+			conn.MarkBad()
+			return "", fmt.Errorf("获取文件夹列表失败: %w", err)
+		}
+		trash := imapClient.FindSpecialUseFolder(folders, imap.MailboxAttrTrash)
+		if trash == nil {
+			return "", fmt.Errorf("服务器未标识废纸篓文件夹（\\Trash），请改用移动到指定文件夹")
+		}
+		return trash.FullPath, nil
+	default:
+		return "", fmt.Errorf("不支持的删除方式: %s", req.GetDeleteMode())
+	}
+}
+
 // Cancel 取消清理
 func (c *Cleaner) Cancel() {
 	c.mu.Lock()
@@ -168,12 +382,8 @@ func (c *Cleaner) Cancel() {
 	}
 }
 
-// sendProgress 发送进度
+// sendProgress 上报进度，由进度聚合器合并后按间隔发布
 func (c *Cleaner) sendProgress(progress *model.CleanProgress) {
-	select {
-	case c.progressCh <- progress:
-	default:
-		// 通道满了就丢弃
-	}
+	c.progress.update(progress)
 }
 
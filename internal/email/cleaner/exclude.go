@@ -0,0 +1,113 @@
+package cleaner
+
+import (
+	"fmt"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+
+	imapClient "CleanMyEmail/internal/email/imap"
+)
+
+// senderFilterResult 按排除项和受保护发件人过滤的结果
+type senderFilterResult struct {
+	uids      []imap.UID
+	excluded  int // 被排除项去掉的邮件数
+	protected int // 受保护发件人的邮件数
+}
+
+// needsSenderFilter 是否需要按排除项或受保护发件人过滤
+func (c *Cleaner) needsSenderFilter(ctx *cleanFolderContext) bool {
+	return !ctx.req.Exclusions.IsEmpty() || !c.protected.IsEmpty()
+}
+
+// filterSenders 从匹配结果中去掉排除的 UID、发件人和域名，以及受保护发件人的邮件
+// 按发件人/域名过滤时需要分批获取邮件头；同时命中两者的邮件计为受保护
+func (c *Cleaner) filterSenders(conn *imapClient.PooledConn, ctx *cleanFolderContext, uids []imap.UID) (*senderFilterResult, *imapClient.PooledConn, error) {
+	res := &senderFilterResult{uids: uids}
+	exclusions := ctx.req.Exclusions
+
+	if excluded := exclusions.FolderUIDs(ctx.folderName); len(excluded) > 0 {
+		set := make(map[imap.UID]struct{}, len(excluded))
+		for _, uid := range excluded {
+			set[imap.UID(uid)] = struct{}{}
+		}
+		kept := make([]imap.UID, 0, len(uids))
+		for _, uid := range uids {
+			if _, ok := set[uid]; !ok {
+				kept = append(kept, uid)
+			}
+		}
+		res.excluded = len(uids) - len(kept)
+		uids = kept
+		res.uids = kept
+	}
+
+	if (!exclusions.HasSenderRules() && c.protected.IsEmpty()) || len(uids) == 0 {
+		return res, conn, nil
+	}
+
+	kept := make([]imap.UID, 0, len(uids))
+	for i := 0; i < len(uids); i += fetchBatchSize {
+		if c.ctx.Err() != nil {
+			return nil, conn, fmt.Errorf("操作已取消")
+		}
+
+		end := min(i+fetchBatchSize, len(uids))
+		uidSet := imap.UIDSet{}
+		uidSet.AddNum(uids[i:end]...)
+
+		var batch []imap.UID
+		var excluded, protected int
+		result, err := c.retryWithReconnect(conn, ctx.folderName, func(cli *imapclient.Client) error {
+			batch, excluded, protected = batch[:0], 0, 0
+			if err := c.throttle(); err != nil {
+				return err
+			}
+			fetchCmd := cli.Fetch(uidSet, &imap.FetchOptions{Envelope: true})
+			for msg := fetchCmd.Next(); msg != nil; msg = fetchCmd.Next() {
+				var msgUID imap.UID
+				var from []imap.Address
+				for item := msg.Next(); item != nil; item = msg.Next() {
+					switch data := item.(type) {
+					case imapclient.FetchItemDataUID:
+						msgUID = data.UID
+					case imapclient.FetchItemDataEnvelope:
+						if data.Envelope != nil {
+							from = data.Envelope.From
+						}
+					}
+				}
+				switch {
+				case msgUID == 0:
+				case anyAddress(from, c.protected.Protects):
+					protected++
+				case anyAddress(from, exclusions.ExcludesSender):
+					excluded++
+				default:
+					batch = append(batch, msgUID)
+				}
+			}
+			return fetchCmd.Close()
+		})
+		if err != nil {
+			return nil, conn, fmt.Errorf("获取邮件头失败: %w", err)
+		}
+		conn = result.conn
+		kept = append(kept, batch...)
+		res.excluded += excluded
+		res.protected += protected
+	}
+	res.uids = kept
+	return res, conn, nil
+}
+
+// anyAddress 是否有任一地址满足条件（From 可能包含多个地址）
+func anyAddress(addrs []imap.Address, match func(addr string) bool) bool {
+	for _, addr := range addrs {
+		if match(addr.Addr()) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package cleaner
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
@@ -11,13 +12,16 @@ import (
 	"github.com/emersion/go-imap/v2/imapclient"
 
 	imapClient "CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/email/query"
 	"CleanMyEmail/internal/model"
 )
 
 const (
-	maxRetries       = 3               // 最大重试次数
-	retryInterval    = 2 * time.Second // 重试间隔
-	fetchBatchSize   = 100             // 获取邮件头的批次大小
+	maxRetries         = 3                // 最大重试次数
+	retryInterval      = 2 * time.Second  // 重试间隔
+	fetchBatchSize     = 100              // 获取邮件头的批次大小
+	maxThrottleRetries = 5                // 服务器限流时的最大重试次数（不计入 maxRetries）
+	throttleInterval   = 10 * time.Second // 没有限速器时限流后的等待时间
 )
 
 // retryResult 重试操作的结果
@@ -40,6 +44,7 @@ func (c *Cleaner) retryWithReconnect(
 ) (*retryResult, error) {
 	client := conn.Client()
 	var lastErr error
+	throttled := 0
 
 	for retry := 0; retry < maxRetries; retry++ {
 		if c.ctx.Err() != nil {
@@ -52,6 +57,15 @@ func (c *Cleaner) retryWithReconnect(
 			lastErr = err
 		}
 
+		// 服务器限流：连接本身没有问题，退避后在同一连接上重试，重连只会加重限流
+		if imapClient.IsThrottled(lastErr) && throttled < maxThrottleRetries {
+			throttled++
+			retry--
+			delay := c.backoff()
+			log.Printf("[DEBUG] [%s] 服务器限流，%v 后重试 (%d/%d): %v", folderName, delay, throttled, maxThrottleRetries, lastErr)
+			continue
+		}
+
 		if retry < maxRetries-1 {
 			log.Printf("[DEBUG] 操作失败，%v 后重试 (%d/%d): %v", retryInterval, retry+1, maxRetries, lastErr)
 			time.Sleep(retryInterval)
@@ -74,6 +88,33 @@ func (c *Cleaner) retryWithReconnect(
 	return nil, fmt.Errorf("操作失败，已重试 %d 次: %w", maxRetries, lastErr)
 }
 
+// throttle 执行 IMAP 命令前等待限速器的令牌，连接池未设置限速器时不等待；清理取消时返回错误
+func (c *Cleaner) throttle() error {
+	if c.ctx.Err() != nil {
+		return fmt.Errorf("操作已取消")
+	}
+	if c.pool == nil {
+		return nil
+	}
+	if limiter := c.pool.Limiter(); limiter != nil {
+		if err := limiter.Wait(c.ctx); err != nil {
+			return fmt.Errorf("操作已取消")
+		}
+	}
+	return nil
+}
+
+// backoff 服务器限流后退避：有限速器时暂停同一主机的所有命令（下次 throttle 时等待），否则直接等待
+func (c *Cleaner) backoff() time.Duration {
+	if c.pool != nil {
+		if limiter := c.pool.Limiter(); limiter != nil {
+			return limiter.Backoff()
+		}
+	}
+	time.Sleep(throttleInterval)
+	return throttleInterval
+}
+
 // getConnection 从连接池获取连接（带重试）
 func (c *Cleaner) getConnection() (*imapClient.PooledConn, error) {
 	var lastErr error
@@ -175,18 +216,53 @@ type cleanFolderContext struct {
 	folderName   string
 	folderIdx    int
 	totalFolders int
-	batchSize    int
 	startDate    time.Time
-	endDate      time.Time
+	endDate      time.Time // 不含当天的截止日期（结束日期的下一天）
 	req          *model.CleanRequest
 	senders      []string
-	subject      string // 主题关键词
+	subject      string     // 主题关键词
+	query        query.Node // 筛选表达式，未设置时为 nil
+	queryTime    time.Time  // 筛选表达式中相对时间的基准
+	deleteMode   model.DeleteMode
+	targetFolder string      // 移动类删除的目标文件夹
+	uidValidity  uint32      // 当前选中文件夹的 UIDVALIDITY
+	protectFlags []imap.Flag // 受保护的标记，带有任一标记的邮件不会被删除
+	action       model.CleanAction
+	dateBasis    model.DateBasis
+	stripped     map[imap.UID]int64     // 移除附件时已处理、原邮件尚未删除的邮件及释放的字节数，-1 表示没有可移除的附件
+	copied       map[imap.UID]struct{}  // 不支持 MOVE 时已复制到目标文件夹、尚未从原文件夹清除的邮件
+	deleteSizer  *batchSizer            // 删除的批大小
+	fetchSizer   *batchSizer            // 客户端过滤获取邮件头的批大小
+	throttleWait time.Duration          // 删除时累计等待限速的时间，用于从命令耗时中扣除
+	processed    int64                  // 已删除邮件的原始大小（字节）
+	checkpoint   *model.CleanCheckpoint // 最近一次保存的检查点，未启用检查点时为 nil
 }
 
-// buildBaseCriteria 构建基础搜索条件（仅日期、大小、已读状态）
-func (c *Cleaner) buildBaseCriteria(ctx *cleanFolderContext) *imap.SearchCriteria {
-	criteria := &imap.SearchCriteria{
-		Before: ctx.endDate.AddDate(0, 0, 1), // BEFORE 是"严格早于"，需要 +1 天
+// actionName 返回删除动作的描述（用于进度消息）
+func (ctx *cleanFolderContext) actionName() string {
+	if ctx.action == model.CleanActionStripAttachments {
+		return "移除附件"
+	}
+	switch ctx.deleteMode {
+	case model.DeleteModeTrash:
+		return "移到废纸篓"
+	case model.DeleteModeFolder:
+		return "移动"
+	default:
+		return "删除"
+	}
+}
+
+// hasHeaderFilters 是否有依赖服务端邮件头搜索的筛选条件
+func (ctx *cleanFolderContext) hasHeaderFilters() bool {
+	return len(ctx.senders) > 0 || ctx.subject != "" || (ctx.query != nil && query.HasHeaderTerms(ctx.query))
+}
+
+// buildFieldCriteria 构建日期、大小、已读状态的搜索条件
+func (c *Cleaner) buildFieldCriteria(ctx *cleanFolderContext) *imap.SearchCriteria {
+	criteria := &imap.SearchCriteria{}
+	if !ctx.endDate.IsZero() {
+		criteria.Before = ctx.endDate // BEFORE 是"严格早于"，endDate 已是结束日期的下一天
 	}
 	if !ctx.startDate.IsZero() {
 		criteria.Since = ctx.startDate
@@ -212,9 +288,38 @@ func (c *Cleaner) buildBaseCriteria(ctx *cleanFolderContext) *imap.SearchCriteri
 	return criteria
 }
 
-// buildFullCriteria 构建完整搜索条件（含发件人和主题）
+// buildBaseCriteria 构建基础搜索条件（不含邮件头条件，用于客户端回退）
+// 筛选表达式只取服务端一定支持的部分，结果是完整条件的超集
+func (c *Cleaner) buildBaseCriteria(ctx *cleanFolderContext) *imap.SearchCriteria {
+	criteria := c.buildRelaxedCriteria(ctx)
+	criteria.NotFlag = append(criteria.NotFlag, ctx.protectFlags...)
+	return criteria
+}
+
+// buildRelaxedCriteria 构建不含邮件头条件和保护标记的搜索条件
+func (c *Cleaner) buildRelaxedCriteria(ctx *cleanFolderContext) *imap.SearchCriteria {
+	criteria := c.buildFieldCriteria(ctx)
+	if ctx.query != nil {
+		if relaxed := query.Relax(ctx.query, ctx.queryTime); relaxed != nil {
+			query.And(criteria, relaxed)
+		}
+	}
+	if ctx.dateBasis == model.DateBasisSent {
+		query.UseSentDate(criteria)
+	}
+	return criteria
+}
+
+// buildFullCriteria 构建完整搜索条件（含发件人、主题、筛选表达式和保护标记）
 func (c *Cleaner) buildFullCriteria(ctx *cleanFolderContext) *imap.SearchCriteria {
-	criteria := c.buildBaseCriteria(ctx)
+	criteria := c.buildMatchCriteria(ctx)
+	criteria.NotFlag = append(criteria.NotFlag, ctx.protectFlags...)
+	return criteria
+}
+
+// buildMatchCriteria 构建不含保护标记的完整搜索条件
+func (c *Cleaner) buildMatchCriteria(ctx *cleanFolderContext) *imap.SearchCriteria {
+	criteria := c.buildFieldCriteria(ctx)
 
 	// 主题筛选
 	if ctx.subject != "" {
@@ -235,38 +340,52 @@ func (c *Cleaner) buildFullCriteria(ctx *cleanFolderContext) *imap.SearchCriteri
 		}
 	}
 
+	// 筛选表达式
+	if ctx.query != nil {
+		query.And(criteria, query.Compile(ctx.query, ctx.queryTime))
+	}
+	if ctx.dateBasis == model.DateBasisSent {
+		query.UseSentDate(criteria)
+	}
+
 	return criteria
 }
 
 // searchEmails 搜索邮件，返回 UID 列表和是否需要客户端过滤
 func (c *Cleaner) searchEmails(conn *imapClient.PooledConn, ctx *cleanFolderContext) (*searchResult, *retryResult, error) {
 	var result searchResult
-	hasFilters := len(ctx.senders) > 0 || ctx.subject != ""
+	hasFilters := ctx.hasHeaderFilters()
 
 	retryRes, err := c.retryWithReconnect(conn, ctx.folderName, func(cli *imapclient.Client) error {
-		// 检查是否已取消
-		if c.ctx.Err() != nil {
-			return fmt.Errorf("操作已取消")
+		// 等待限速（取消时返回错误）
+		if err := c.throttle(); err != nil {
+			return err
 		}
 
 		// 先尝试完整的服务端搜索
 		criteria := c.buildFullCriteria(ctx)
-		log.Printf("[DEBUG] [%s] 搜索条件: Since=%s, Before=%s, Header=%+v, Or=%v, senders=%v, subject=%s",
+		log.Printf("[DEBUG] [%s] 搜索条件: Since=%s, Before=%s, SentSince=%s, SentBefore=%s, Header=%+v, Or=%v, senders=%v, subject=%s",
 			ctx.folderName, formatDate(criteria.Since), formatDate(criteria.Before),
+			formatDate(criteria.SentSince), formatDate(criteria.SentBefore),
 			criteria.Header, criteria.Or != nil, ctx.senders, ctx.subject)
 
 		searchData, err := cli.UIDSearch(criteria, nil).Wait()
-		if err != nil {
+		switch {
+		case err == nil:
+			result.uids = searchData.AllUIDs()
+			log.Printf("[DEBUG] [%s] 服务端搜索结果: 找到 %d 封邮件", ctx.folderName, len(result.uids))
+		case ctx.req.EnableClientFallback && ctx.query != nil && isCommandRejected(err):
+			// 服务器拒绝了筛选表达式中的 OR/NOT/HEADER 组合，改用基础条件 + 客户端过滤
+			log.Printf("[DEBUG] [%s] 服务端不支持筛选表达式: %v", ctx.folderName, err)
+			result.uids = nil
+		default:
 			return err
 		}
-		result.uids = searchData.AllUIDs()
-		log.Printf("[DEBUG] [%s] 服务端搜索结果: 找到 %d 封邮件", ctx.folderName, len(result.uids))
 
 		// 如果启用了客户端回退，且有筛选条件但服务端返回 0，可能是服务器不支持某些搜索
-		if ctx.req.EnableClientFallback && len(result.uids) == 0 && hasFilters {
-			// 检查是否已取消
-			if c.ctx.Err() != nil {
-				return fmt.Errorf("操作已取消")
+		if ctx.req.EnableClientFallback && len(result.uids) == 0 && (hasFilters || err != nil) {
+			if err := c.throttle(); err != nil {
+				return err
 			}
 
 			baseCriteria := c.buildBaseCriteria(ctx)
@@ -275,14 +394,7 @@ func (c *Cleaner) searchEmails(conn *imapClient.PooledConn, ctx *cleanFolderCont
 				return baseErr
 			}
 			if baseUIDs := baseData.AllUIDs(); len(baseUIDs) > 0 {
-				var filterDesc string
-				if len(ctx.senders) > 0 && ctx.subject != "" {
-					filterDesc = "发件人/主题"
-				} else if len(ctx.senders) > 0 {
-					filterDesc = "发件人"
-				} else {
-					filterDesc = "主题"
-				}
+				filterDesc := ctx.filterDesc()
 				log.Printf("[DEBUG] [%s] 服务端不支持 %s 搜索，回退到客户端过滤 (%d 封)", ctx.folderName, filterDesc, len(baseUIDs))
 				result.uids = baseUIDs
 				result.needClientFilter = true
@@ -302,46 +414,216 @@ func (c *Cleaner) searchEmails(conn *imapClient.PooledConn, ctx *cleanFolderCont
 	return &result, retryRes, err
 }
 
-// deleteEmailBatches 分批删除邮件
-func (c *Cleaner) deleteEmailBatches(conn *imapClient.PooledConn, ctx *cleanFolderContext, uids []imap.UID, stat *model.FolderCleanStat) {
-	totalBatches := (len(uids) + ctx.batchSize - 1) / ctx.batchSize
+// deleteEmailBatches 分批删除邮件，返回最后使用的连接（重连或暂停后会更换连接，可能为 nil）
+// 批大小由 deleteSizer 决定，自适应模式下按服务器响应调整
+func (c *Cleaner) deleteEmailBatches(conn *imapClient.PooledConn, ctx *cleanFolderContext, uids []imap.UID, stat *model.FolderCleanStat) *imapClient.PooledConn {
+	stat.ExpungeMode = expungeModeFor(conn.Client(), ctx.deleteMode)
+	if stat.ExpungeMode == model.ExpungeModeFull {
+		c.warnFullExpunge(conn, ctx, uids, stat)
+	}
+	c.saveCheckpoint(ctx, stat, uids, "running")
 
-	for batch := 0; batch < totalBatches; batch++ {
+	batch := 0
+	for start := 0; start < len(uids); {
 		if c.ctx.Err() != nil {
 			stat.Status = "cancelled"
-			return
+			return conn
+		}
+
+		// 暂停时在批次之间归还连接，继续后从下一批接着删除
+		var err error
+		if conn, err = c.pauseBetweenBatches(conn, ctx, stat); err != nil {
+			stat.Status = "failed"
+			stat.Error = err.Error()
+			return nil
+		} else if conn == nil {
+			stat.Status = "cancelled"
+			return nil
 		}
 
-		start := batch * ctx.batchSize
-		end := min(start+ctx.batchSize, len(uids))
+		batch++
+		end := min(start+ctx.deleteSizer.size(), len(uids))
 		batchUIDs := uids[start:end]
 
-		var deleted int
-		result, err := c.retryWithReconnect(conn, ctx.folderName, func(cli *imapclient.Client) error {
-			var deleteErr error
-			deleted, deleteErr = c.deleteBatch(cli, batchUIDs)
-			return deleteErr
-		})
+		// 先备份，确认落盘后再删除
+		if c.archiver != nil {
+			var archived []imap.UID
+			result, err := c.retryWithReconnect(conn, ctx.folderName, func(cli *imapclient.Client) error {
+				var archiveErr error
+				archived, archiveErr = c.archiveBatch(cli, ctx, batchUIDs)
+				return archiveErr
+			})
+			if err != nil {
+				stat.Status = "failed"
+				stat.Error = fmt.Sprintf("备份失败: %v", err)
+				return conn
+			}
+			conn = result.conn
+			batchUIDs = archived
+		}
+
+		// 移除附件：先上传去掉附件的邮件，只删除已上传成功的原邮件
+		var reclaimed int64
+		if ctx.action == model.CleanActionStripAttachments {
+			var stripped []imap.UID
+			result, err := c.retryWithReconnect(conn, ctx.folderName, func(cli *imapclient.Client) error {
+				var stripErr error
+				stripped, reclaimed, stripErr = c.stripBatch(cli, ctx, batchUIDs)
+				return stripErr
+			})
+			if err != nil {
+				stat.Status = "failed"
+				stat.Error = fmt.Sprintf("移除附件失败: %v", err)
+				return conn
+			}
+			conn = result.conn
+			batchUIDs = stripped
+		}
+
+		deleted, newConn, err := c.deleteInChunks(conn, ctx, batchUIDs)
+		conn = newConn
+		stat.DeletedCount += deleted
+		stat.ProcessedBytes = ctx.processed
 		if err != nil {
 			stat.Status = "failed"
-			stat.Error = fmt.Sprintf("删除失败: %v", err)
-			return
+			stat.Error = fmt.Sprintf("%s失败: %v", ctx.actionName(), err)
+			return conn
 		}
-		conn = result.conn
 
-		stat.DeletedCount += deleted
+		stat.ReclaimedBytes += reclaimed
+		c.saveCheckpoint(ctx, stat, uids[end:], "running")
+		// 自适应模式下总批次数按当前批大小估算
+		size := ctx.deleteSizer.size()
+		totalBatches := batch + (len(uids)-end+size-1)/size
 		c.sendProgress(&model.CleanProgress{
-			CurrentFolder: ctx.folderName,
-			FolderIndex:   ctx.folderIdx + 1,
-			TotalFolders:  ctx.totalFolders,
-			CurrentBatch:  batch + 1,
-			TotalBatches:  totalBatches,
-			DeletedCount:  stat.DeletedCount,
-			MatchedCount:  stat.MatchedCount,
-			Status:        "running",
-			Message:       fmt.Sprintf("文件夹 %s: 批次 %d/%d 完成，已删除 %d 封", ctx.folderName, batch+1, totalBatches, stat.DeletedCount),
+			CurrentFolder:  ctx.folderName,
+			FolderIndex:    ctx.folderIdx + 1,
+			TotalFolders:   ctx.totalFolders,
+			CurrentBatch:   batch,
+			TotalBatches:   totalBatches,
+			BatchSize:      size,
+			DeletedCount:   stat.DeletedCount,
+			MatchedCount:   stat.MatchedCount,
+			ProcessedBytes: stat.ProcessedBytes,
+			Status:         "running",
+			Message:        fmt.Sprintf("文件夹 %s: 批次 %d/%d 完成，已%s %d 封", ctx.folderName, batch, totalBatches, ctx.actionName(), stat.DeletedCount),
+		})
+		start = end
+	}
+
+	c.saveCheckpoint(ctx, stat, nil, "completed")
+	return conn
+}
+
+// deleteInChunks 按当前批大小分段删除，每段单独重试；自适应模式下失败后以更小的批重试
+// 返回已删除的数量和最后使用的连接，失败时已删除的部分同样计入
+func (c *Cleaner) deleteInChunks(conn *imapClient.PooledConn, ctx *cleanFolderContext, uids []imap.UID) (int, *imapClient.PooledConn, error) {
+	deleted := 0
+	for len(uids) > 0 {
+		var chunkLen, chunkDeleted int
+		var chunkBytes int64
+		var chunkRecords []*model.DeletedMessage
+		var auditErr error
+		result, err := c.retryWithReconnect(conn, ctx.folderName, func(cli *imapclient.Client) error {
+			chunk := uids[:min(ctx.deleteSizer.size(), len(uids))]
+			size, records, err := c.fetchDeletedInfo(cli, ctx, chunk)
+			if err != nil {
+				return err
+			}
+			// 审计记录必须在删除前写入；写入失败不是连接问题，不重试，直接结束
+			if auditErr = c.recordAudit(ctx, records); auditErr != nil {
+				return nil
+			}
+			// 耗时不含等待限速的时间
+			waited := ctx.throttleWait
+			started := time.Now()
+			n, err := c.deleteBatch(cli, ctx, chunk)
+			ctx.deleteSizer.observe(time.Since(started)-(ctx.throttleWait-waited), err)
+			if err != nil {
+				// 重试时会重新获取并记录这一段
+				c.finishAudit(ctx, records, model.DeletedMessageFailed)
+			}
+			chunkLen, chunkDeleted, chunkBytes, chunkRecords = len(chunk), n, size, records
+			return err
 		})
+		if err != nil {
+			return deleted, conn, err
+		}
+		conn = result.conn
+		if auditErr != nil {
+			return deleted, conn, auditErr
+		}
+		deleted += chunkDeleted
+		ctx.processed += chunkBytes
+		c.finishAudit(ctx, chunkRecords, model.DeletedMessageDeleted)
+		// 原邮件已删除，去附件副本不再需要记录到检查点
+		for _, uid := range uids[:chunkLen] {
+			delete(ctx.stripped, uid)
+		}
+		uids = uids[chunkLen:]
 	}
+	return deleted, conn, nil
+}
+
+// expungeModeFor 根据服务器能力确定清除方式
+func expungeModeFor(client *imapclient.Client, deleteMode model.DeleteMode) model.ExpungeMode {
+	caps := client.Caps()
+	switch {
+	case deleteMode.IsMove() && caps.Has(imap.CapMove):
+		return model.ExpungeModeMove
+	case caps.Has(imap.CapUIDPlus):
+		return model.ExpungeModeUID
+	default:
+		return model.ExpungeModeFull
+	}
+}
+
+// warnFullExpunge 服务器不支持 UIDPLUS 时，统计文件夹中已被其他客户端标记 \Deleted 的邮件并提示风险
+// 普通 EXPUNGE 会把这些不在筛选结果中的邮件一并永久删除
+func (c *Cleaner) warnFullExpunge(conn *imapClient.PooledConn, ctx *cleanFolderContext, uids []imap.UID, stat *model.FolderCleanStat) {
+	matched := make(map[imap.UID]struct{}, len(uids))
+	for _, uid := range uids {
+		matched[uid] = struct{}{}
+	}
+
+	var unrelated int
+	if err := c.throttle(); err != nil {
+		return
+	}
+	data, err := conn.Client().UIDSearch(&imap.SearchCriteria{Flag: []imap.Flag{imap.FlagDeleted}}, nil).Wait()
+	if err != nil {
+		log.Printf("[WARN] [%s] 查询已标记删除的邮件失败: %v", ctx.folderName, err)
+	} else {
+		for _, uid := range data.AllUIDs() {
+			if _, ok := matched[uid]; !ok {
+				unrelated++
+			}
+		}
+	}
+
+	warning := "服务器不支持 UIDPLUS，EXPUNGE 会同时永久删除其他客户端标记删除的邮件"
+	if unrelated > 0 {
+		warning = fmt.Sprintf("服务器不支持 UIDPLUS，EXPUNGE 会同时永久删除文件夹中另外 %d 封已被标记删除的邮件", unrelated)
+	}
+	addWarning(stat, warning)
+	log.Printf("[WARN] [%s] %s", ctx.folderName, warning)
+
+	c.sendProgress(&model.CleanProgress{
+		CurrentFolder: ctx.folderName,
+		FolderIndex:   ctx.folderIdx + 1,
+		TotalFolders:  ctx.totalFolders,
+		MatchedCount:  stat.MatchedCount,
+		Status:        "running",
+		Message:       fmt.Sprintf("文件夹 %s: %s", ctx.folderName, warning),
+	})
+}
+
+// addWarning 追加风险提示
+func addWarning(stat *model.FolderCleanStat, warning string) {
+	if stat.Warning != "" {
+		stat.Warning += "；"
+	}
+	stat.Warning += warning
 }
 
 // sendNoMatchProgress 发送无匹配邮件的进度
@@ -357,28 +639,58 @@ func (c *Cleaner) sendNoMatchProgress(ctx *cleanFolderContext, message string) {
 }
 
 // cleanFolder 清理单个文件夹
-func (c *Cleaner) cleanFolder(folderName string, startDate, endDate time.Time, req *model.CleanRequest, folderIdx, totalFolders, batchSize int) model.FolderCleanStat {
+func (c *Cleaner) cleanFolder(folderName string, startDate, endDate time.Time, req *model.CleanRequest, folderIdx, totalFolders, batchSize int, targetFolder string) model.FolderCleanStat {
 	ctx := &cleanFolderContext{
 		folderName:   folderName,
 		folderIdx:    folderIdx,
 		totalFolders: totalFolders,
-		batchSize:    batchSize,
 		startDate:    startDate,
 		endDate:      endDate,
 		req:          req,
 		senders:      parseSenders(req.FilterSender),
 		subject:      strings.TrimSpace(req.FilterSubject),
+		query:        c.filterQuery,
+		queryTime:    c.queryTime,
+		deleteMode:   req.GetDeleteMode(),
+		targetFolder: targetFolder,
+		action:       req.GetAction(),
+		dateBasis:    req.GetDateBasis(),
+		stripped:     make(map[imap.UID]int64),
+		deleteSizer:  newBatchSizer(batchSize, req.AdaptiveBatch, adaptiveBatchLimit(req)),
+		fetchSizer:   newBatchSizer(fetchBatchSize, req.AdaptiveBatch, adaptiveMaxFetchBatch),
+	}
+	for _, flag := range req.ProtectedFlags() {
+		ctx.protectFlags = append(ctx.protectFlags, imap.Flag(flag))
 	}
 
 	stat := model.FolderCleanStat{Folder: folderName, Status: "completed"}
 
+	// 移动类删除时跳过目标文件夹本身
+	if !req.PreviewOnly && ctx.deleteMode.IsMove() && folderName == targetFolder {
+		stat.Status = "skipped"
+		stat.Error = "目标文件夹本身不参与移动"
+		c.sendNoMatchProgress(ctx, fmt.Sprintf("文件夹 %s 是移动目标，已跳过", folderName))
+		return stat
+	}
+
+	// 暂停时尚未开始的文件夹不获取连接
+	if !c.waitResume(ctx, &stat) {
+		stat.Status = "cancelled"
+		return stat
+	}
+
 	// 获取连接
 	conn, err := c.getConnection()
 	if err != nil {
 		stat.Status, stat.Error = "failed", fmt.Sprintf("获取连接失败: %v", err)
 		return stat
 	}
-	defer conn.Release()
+	// 重试和暂停会更换连接，结束时归还当前使用的连接
+	defer func() {
+		if conn != nil {
+			conn.Release()
+		}
+	}()
 
 	// 选择文件夹
 	mbox, err := conn.Client().Select(folderName, nil).Wait()
@@ -387,49 +699,132 @@ func (c *Cleaner) cleanFolder(folderName string, startDate, endDate time.Time, r
 		return stat
 	}
 
+	ctx.uidValidity = mbox.UIDValidity
+
+	// 从检查点继续：UIDVALIDITY 未变化时只处理上次剩余的 UID，变化时检查点作废，重新搜索
+	var remaining []imap.UID
+	resumed := false
+	if cp := c.checkpoints[folderName]; cp != nil && !req.PreviewOnly {
+		stat.DeletedCount = cp.DeletedCount
+		if cp.Status == "completed" {
+			stat.MatchedCount = cp.MatchedCount
+			c.sendNoMatchProgress(ctx, fmt.Sprintf("文件夹 %s 已在上次运行中完成", folderName))
+			return stat
+		}
+		if cp.UIDValidity == mbox.UIDValidity {
+			if remaining, err = parseUIDs(cp.RemainingUIDs); err != nil {
+				log.Printf("[WARN] [%s] 解析检查点失败: %v，重新搜索", folderName, err)
+			} else {
+				resumed = true
+				// 上次已上传去附件副本的邮件只删除原邮件，不再上传（释放的字节数未记录，按 0 计）
+				if stripped, err := parseUIDs(cp.StrippedUIDs); err != nil {
+					log.Printf("[WARN] [%s] 解析检查点中已移除附件的邮件失败: %v", folderName, err)
+				} else {
+					for _, uid := range stripped {
+						ctx.stripped[uid] = 0
+					}
+				}
+			}
+		} else {
+			log.Printf("[WARN] [%s] UIDVALIDITY 已变化 (%d -> %d)，检查点作废", folderName, cp.UIDValidity, mbox.UIDValidity)
+			addWarning(&stat, "UIDVALIDITY 已变化，已忽略上次的进度并重新搜索")
+		}
+	}
+
 	if mbox.NumMessages == 0 {
 		c.sendNoMatchProgress(ctx, fmt.Sprintf("文件夹 %s 为空", folderName))
 		return stat
 	}
 
-	// 搜索邮件
-	searchRes, retryRes, err := c.searchEmails(conn, ctx)
-	if err != nil {
-		stat.Status, stat.Error = "failed", fmt.Sprintf("搜索邮件失败: %v", err)
-		return stat
+	// 搜索邮件（按清理计划执行时直接使用计划中的 UID）
+	var uids []imap.UID
+	if c.plan != nil && !req.PreviewOnly {
+		if uids, stat.ProtectedCount, conn, err = c.planUIDs(conn, ctx); err != nil {
+			stat.Status, stat.Error = "failed", err.Error()
+			return stat
+		}
+	} else {
+		searchRes, retryRes, err := c.searchEmails(conn, ctx)
+		if err != nil {
+			stat.Status, stat.Error = "failed", fmt.Sprintf("搜索邮件失败: %v", err)
+			return stat
+		}
+		conn = retryRes.conn
+		uids = searchRes.uids
+
+		// 统计因保护标记而跳过的邮件
+		if len(ctx.protectFlags) > 0 {
+			stat.ProtectedCount += c.countProtectedFlags(conn, ctx, searchRes.needClientFilter)
+		}
+
+		// 客户端过滤（如果服务端不支持发件人/主题搜索）
+		if len(uids) > 0 && searchRes.needClientFilter {
+			if filteredUIDs, err := c.filterByEnvelope(conn, ctx, uids); err != nil {
+				// 如果是取消操作，直接返回
+				if c.ctx.Err() != nil {
+					stat.Status = "cancelled"
+					return stat
+				}
+				log.Printf("[WARN] [%s] 客户端过滤失败: %v，跳过筛选", folderName, err)
+			} else {
+				log.Printf("[DEBUG] [%s] 客户端过滤: %d -> %d 封邮件", folderName, len(uids), len(filteredUIDs))
+				uids = filteredUIDs
+			}
+		}
 	}
-	conn = retryRes.conn
-	uids := searchRes.uids
 
 	if len(uids) == 0 {
 		c.sendNoMatchProgress(ctx, fmt.Sprintf("文件夹 %s 没有符合条件的邮件", folderName))
 		return stat
 	}
 
-	// 客户端过滤（如果服务端不支持发件人/主题搜索）
-	if searchRes.needClientFilter {
-		if filteredUIDs, err := c.filterByEnvelope(conn, ctx, uids); err != nil {
-			// 如果是取消操作，直接返回
+	// 只保留检查点中尚未删除的邮件
+	if resumed {
+		uids = intersectUIDs(uids, remaining)
+		log.Printf("[DEBUG] [%s] 从检查点继续: 剩余 %d 封", folderName, len(uids))
+	}
+
+	// 去掉排除的邮件和发件人，跳过受保护发件人的邮件
+	if c.needsSenderFilter(ctx) {
+		filtered, newConn, err := c.filterSenders(conn, ctx, uids)
+		if err != nil {
 			if c.ctx.Err() != nil {
 				stat.Status = "cancelled"
-				return stat
+			} else {
+				stat.Status, stat.Error = "failed", fmt.Sprintf("过滤排除项和受保护发件人失败: %v", err)
 			}
-			log.Printf("[WARN] [%s] 客户端过滤失败: %v，跳过筛选", folderName, err)
-		} else {
-			log.Printf("[DEBUG] [%s] 客户端过滤: %d -> %d 封邮件", folderName, len(uids), len(filteredUIDs))
-			uids = filteredUIDs
+			return stat
+		}
+		conn = newConn
+		stat.ExcludedCount = filtered.excluded
+		stat.ProtectedCount += filtered.protected
+		if filtered.excluded > 0 || filtered.protected > 0 {
+			log.Printf("[DEBUG] [%s] 排除 %d 封，受保护 %d 封", folderName, filtered.excluded, filtered.protected)
 		}
+		uids = filtered.uids
 	}
 
-	stat.MatchedCount = len(uids)
+	stat.MatchedCount = stat.DeletedCount + len(uids)
 
 	if len(uids) == 0 {
 		c.sendNoMatchProgress(ctx, fmt.Sprintf("文件夹 %s 没有符合条件的邮件", folderName))
 		return stat
 	}
 
-	// 预览模式
+	// 预览模式：获取邮件明细供用户逐封确认
 	if req.PreviewOnly {
+		messages, err := c.fetchPreviewMessages(conn, ctx, uids)
+		if err != nil {
+			if c.ctx.Err() != nil {
+				stat.Status = "cancelled"
+				return stat
+			}
+			log.Printf("[WARN] [%s] 获取预览邮件明细失败: %v", folderName, err)
+			addWarning(&stat, fmt.Sprintf("获取邮件明细失败: %v", err))
+		}
+		c.addPreviewMessages(messages)
+		c.addPlanFolder(ctx, uids)
+
 		c.sendProgress(&model.CleanProgress{
 			CurrentFolder: folderName,
 			FolderIndex:   folderIdx + 1,
@@ -442,7 +837,7 @@ func (c *Cleaner) cleanFolder(folderName string, startDate, endDate time.Time, r
 	}
 
 	// 分批删除
-	c.deleteEmailBatches(conn, ctx, uids, &stat)
+	conn = c.deleteEmailBatches(conn, ctx, uids, &stat)
 	return stat
 }
 
@@ -454,8 +849,8 @@ func min(a, b int) int {
 	return b
 }
 
-// deleteBatch 删除一批邮件
-func (c *Cleaner) deleteBatch(client *imapclient.Client, uids []imap.UID) (int, error) {
+// deleteBatch 按删除方式处理一批邮件
+func (c *Cleaner) deleteBatch(client *imapclient.Client, ctx *cleanFolderContext, uids []imap.UID) (int, error) {
 	if len(uids) == 0 {
 		return 0, nil
 	}
@@ -465,6 +860,13 @@ func (c *Cleaner) deleteBatch(client *imapclient.Client, uids []imap.UID) (int,
 		uidSet.AddNum(uid)
 	}
 
+	if ctx.deleteMode.IsMove() {
+		return c.moveBatch(client, ctx, uids, uidSet)
+	}
+
+	if err := c.throttleFor(ctx); err != nil {
+		return 0, err
+	}
 	if err := client.Store(uidSet, &imap.StoreFlags{
 		Op:    imap.StoreFlagsAdd,
 		Flags: []imap.Flag{imap.FlagDeleted},
@@ -472,52 +874,127 @@ func (c *Cleaner) deleteBatch(client *imapclient.Client, uids []imap.UID) (int,
 		return 0, fmt.Errorf("标记删除失败: %w", err)
 	}
 
-	if err := client.Expunge().Close(); err != nil {
+	if err := c.throttleFor(ctx); err != nil {
+		return 0, err
+	}
+	if err := expunge(client, uidSet).Close(); err != nil {
+		return 0, fmt.Errorf("执行删除失败: %w", err)
+	}
+
+	return len(uids), nil
+}
+
+// moveBatch 将一批邮件移动到目标文件夹
+// 优先使用 MOVE 扩展，不支持时回退到 COPY + 标记删除 + UID EXPUNGE
+// 回退时已复制成功的 UID 记录在 ctx.copied 中，后续步骤失败重试时不再重复复制，避免目标文件夹中出现重复邮件
+func (c *Cleaner) moveBatch(client *imapclient.Client, ctx *cleanFolderContext, uids []imap.UID, uidSet imap.UIDSet) (int, error) {
+	if err := c.throttleFor(ctx); err != nil {
+		return 0, err
+	}
+	if client.Caps().Has(imap.CapMove) {
+		if _, err := client.Move(uidSet, ctx.targetFolder).Wait(); err != nil {
+			return 0, fmt.Errorf("移动邮件失败: %w", err)
+		}
+		return len(uids), nil
+	}
+
+	var toCopy []imap.UID
+	for _, uid := range uids {
+		if _, ok := ctx.copied[uid]; !ok {
+			toCopy = append(toCopy, uid)
+		}
+	}
+	if len(toCopy) > 0 {
+		if _, err := client.Copy(imap.UIDSetNum(toCopy...), ctx.targetFolder).Wait(); err != nil {
+			return 0, fmt.Errorf("复制邮件失败: %w", err)
+		}
+		if ctx.copied == nil {
+			ctx.copied = make(map[imap.UID]struct{})
+		}
+		for _, uid := range toCopy {
+			ctx.copied[uid] = struct{}{}
+		}
+	}
+
+	if err := c.throttleFor(ctx); err != nil {
+		return 0, err
+	}
+	if err := client.Store(uidSet, &imap.StoreFlags{
+		Op:     imap.StoreFlagsAdd,
+		Silent: true,
+		Flags:  []imap.Flag{imap.FlagDeleted},
+	}, nil).Close(); err != nil {
+		return 0, fmt.Errorf("标记删除失败: %w", err)
+	}
+
+	if err := c.throttleFor(ctx); err != nil {
+		return 0, err
+	}
+	if err := expunge(client, uidSet).Close(); err != nil {
 		return 0, fmt.Errorf("执行删除失败: %w", err)
 	}
+	for _, uid := range uids {
+		delete(ctx.copied, uid)
+	}
 
 	return len(uids), nil
 }
 
+// expunge 清除已标记删除的邮件
+// 支持 UIDPLUS 时只清除指定 UID，避免误删其他客户端标记的邮件
+// 不支持时只能执行整个文件夹的 EXPUNGE，会同时清除其他客户端标记了 \Deleted 的邮件。
+// 这是有意保留的行为（否则删除和移动都无法完成）：清除方式记为 full，
+// 开始删除前 warnFullExpunge 会统计受影响的邮件数并在清理结果中提示
+func expunge(client *imapclient.Client, uidSet imap.UIDSet) *imapclient.ExpungeCommand {
+	if client.Caps().Has(imap.CapUIDPlus) {
+		return client.UIDExpunge(uidSet)
+	}
+	return client.Expunge()
+}
+
 // filterByEnvelope 根据发件人和主题过滤邮件（客户端过滤）
 func (c *Cleaner) filterByEnvelope(conn *imapClient.PooledConn, ctx *cleanFolderContext, uids []imap.UID) ([]imap.UID, error) {
 	if len(uids) == 0 {
 		return uids, nil
 	}
 	// 如果没有需要过滤的条件，直接返回
-	if len(ctx.senders) == 0 && ctx.subject == "" {
+	if len(ctx.senders) == 0 && ctx.subject == "" && ctx.query == nil {
 		return uids, nil
 	}
 
 	client := conn.Client()
 	var filteredUIDs []imap.UID
-	totalBatches := (len(uids) + fetchBatchSize - 1) / fetchBatchSize
+	filterDesc := ctx.filterDesc()
 
-	// 构建过滤描述
-	var filterDesc string
-	if len(ctx.senders) > 0 && ctx.subject != "" {
-		filterDesc = "发件人/主题"
-	} else if len(ctx.senders) > 0 {
-		filterDesc = "发件人"
-	} else {
-		filterDesc = "主题"
+	// 筛选表达式可能包含大小、日期和标记条件，需要额外获取这些属性
+	fetchOptions := &imap.FetchOptions{Envelope: true}
+	if ctx.query != nil {
+		fetchOptions = query.FetchOptions()
 	}
 
-	for i := 0; i < len(uids); i += fetchBatchSize {
+	// 批大小由 fetchSizer 决定，自适应模式下失败后以更小的批重试同一位置
+	// 服务器限流时退避后以相同批大小重试，不计入失败重试次数
+	batchNum, retries, throttled := 0, 0, 0
+	for i := 0; i < len(uids); {
 		if c.ctx.Err() != nil {
 			return nil, fmt.Errorf("操作已取消")
 		}
 
-		batchNum := i/fetchBatchSize + 1
-		end := min(i+fetchBatchSize, len(uids))
+		size := ctx.fetchSizer.size()
+		end := min(i+size, len(uids))
 		batchUIDs := uids[i:end]
+		firstTry := retries == 0 && throttled == 0
+		if firstTry {
+			batchNum++
+		}
 
 		// 每 10 批或最后一批发送进度
-		if batchNum%10 == 0 || batchNum == totalBatches {
+		if firstTry && (batchNum%10 == 0 || end == len(uids)) {
 			c.sendProgress(&model.CleanProgress{
 				CurrentFolder: ctx.folderName,
 				FolderIndex:   ctx.folderIdx + 1,
 				TotalFolders:  ctx.totalFolders,
+				BatchSize:     size,
 				Status:        "running",
 				Message:       fmt.Sprintf("文件夹 %s: 过滤%s %d/%d (已匹配 %d 封)", ctx.folderName, filterDesc, end, len(uids), len(filteredUIDs)),
 			})
@@ -528,24 +1005,53 @@ func (c *Cleaner) filterByEnvelope(conn *imapClient.PooledConn, ctx *cleanFolder
 			uidSet.AddNum(uid)
 		}
 
-		fetchCmd := client.Fetch(uidSet, &imap.FetchOptions{Envelope: true})
+		if err := c.throttle(); err != nil {
+			return nil, err
+		}
+		started := time.Now()
+		var matched []imap.UID
+		fetchCmd := client.Fetch(uidSet, fetchOptions)
 		for msg := fetchCmd.Next(); msg != nil; msg = fetchCmd.Next() {
 			var msgUID imap.UID
+			fetched := query.Message{SentDate: ctx.dateBasis == model.DateBasisSent}
 			for item := msg.Next(); item != nil; item = msg.Next() {
 				switch data := item.(type) {
 				case imapclient.FetchItemDataUID:
 					msgUID = data.UID
 				case imapclient.FetchItemDataEnvelope:
-					if c.matchEnvelope(data.Envelope, ctx) {
-						filteredUIDs = append(filteredUIDs, msgUID)
-					}
+					fetched.Envelope = data.Envelope
+				case imapclient.FetchItemDataRFC822Size:
+					fetched.Size = data.Size
+				case imapclient.FetchItemDataFlags:
+					fetched.Flags = data.Flags
+				case imapclient.FetchItemDataInternalDate:
+					fetched.InternalDate = data.Time
 				}
 			}
+			if msgUID != 0 && c.matchEnvelope(fetched.Envelope, ctx) &&
+				(ctx.query == nil || query.Match(ctx.query, &fetched, ctx.queryTime)) {
+				matched = append(matched, msgUID)
+			}
 		}
 
-		if err := fetchCmd.Close(); err != nil {
+		err := fetchCmd.Close()
+		if imapClient.IsThrottled(err) && throttled < maxThrottleRetries {
+			throttled++
+			delay := c.backoff()
+			log.Printf("[DEBUG] [%s] 服务器限流，%v 后重试 (%d/%d): %v", ctx.folderName, delay, throttled, maxThrottleRetries, err)
+			continue
+		}
+		if shrank := ctx.fetchSizer.observe(time.Since(started), err); err != nil {
+			if shrank && retries < maxRetries {
+				retries++
+				log.Printf("[DEBUG] [%s] 获取邮件头失败，批大小减小为 %d 后重试: %v", ctx.folderName, ctx.fetchSizer.size(), err)
+				continue
+			}
 			return nil, fmt.Errorf("获取邮件头失败: %w", err)
 		}
+		retries, throttled = 0, 0
+		filteredUIDs = append(filteredUIDs, matched...)
+		i = end
 	}
 
 	return filteredUIDs, nil
@@ -585,4 +1091,115 @@ func (c *Cleaner) matchEnvelope(envelope *imap.Envelope, ctx *cleanFolderContext
 	}
 
 	return true
-}
\ No newline at end of file
+}
+
+// filterDesc 返回客户端过滤条件的描述（用于进度消息）
+func (ctx *cleanFolderContext) filterDesc() string {
+	var parts []string
+	if len(ctx.senders) > 0 {
+		parts = append(parts, "发件人")
+	}
+	if ctx.subject != "" {
+		parts = append(parts, "主题")
+	}
+	if ctx.query != nil {
+		parts = append(parts, "筛选表达式")
+	}
+	return strings.Join(parts, "/")
+}
+
+// isCommandRejected 服务器是否以 NO/BAD 拒绝了命令（区别于网络错误）
+func isCommandRejected(err error) bool {
+	var imapErr *imap.Error
+	return errors.As(err, &imapErr)
+}
+
+// fetchPreviewMessages 分批获取邮件的发件人、主题、日期、大小和标记（预览模式）
+func (c *Cleaner) fetchPreviewMessages(conn *imapClient.PooledConn, ctx *cleanFolderContext, uids []imap.UID) ([]*model.PreviewMessage, error) {
+	messages := make([]*model.PreviewMessage, 0, len(uids))
+	totalBatches := (len(uids) + fetchBatchSize - 1) / fetchBatchSize
+
+	for i := 0; i < len(uids); i += fetchBatchSize {
+		if c.ctx.Err() != nil {
+			return messages, fmt.Errorf("操作已取消")
+		}
+
+		batchNum := i/fetchBatchSize + 1
+		end := min(i+fetchBatchSize, len(uids))
+
+		// 每 10 批或最后一批发送进度
+		if batchNum%10 == 0 || batchNum == totalBatches {
+			c.sendProgress(&model.CleanProgress{
+				CurrentFolder: ctx.folderName,
+				FolderIndex:   ctx.folderIdx + 1,
+				TotalFolders:  ctx.totalFolders,
+				MatchedCount:  len(uids),
+				Status:        "running",
+				Message:       fmt.Sprintf("预览: 文件夹 %s 获取邮件明细 %d/%d", ctx.folderName, end, len(uids)),
+			})
+		}
+
+		uidSet := imap.UIDSet{}
+		for _, uid := range uids[i:end] {
+			uidSet.AddNum(uid)
+		}
+
+		var batch []*model.PreviewMessage
+		result, err := c.retryWithReconnect(conn, ctx.folderName, func(cli *imapclient.Client) error {
+			batch = batch[:0]
+			if err := c.throttle(); err != nil {
+				return err
+			}
+			fetchCmd := cli.Fetch(uidSet, query.FetchOptions())
+			for msg := fetchCmd.Next(); msg != nil; msg = fetchCmd.Next() {
+				if preview := imapClient.CollectPreviewMessage(msg, ctx.folderName); preview != nil {
+					batch = append(batch, preview)
+				}
+			}
+			return fetchCmd.Close()
+		})
+		if err != nil {
+			return messages, fmt.Errorf("获取邮件头失败: %w", err)
+		}
+		conn = result.conn
+		messages = append(messages, batch...)
+	}
+
+	return messages, nil
+}
+
+// anyFlagCriteria 构建"带有任一标记"的搜索条件
+func anyFlagCriteria(flags []imap.Flag) *imap.SearchCriteria {
+	criteria := &imap.SearchCriteria{Flag: []imap.Flag{flags[len(flags)-1]}}
+	for i := len(flags) - 2; i >= 0; i-- {
+		criteria = &imap.SearchCriteria{Or: [][2]imap.SearchCriteria{{{Flag: []imap.Flag{flags[i]}}, *criteria}}}
+	}
+	return criteria
+}
+
+// countProtectedFlags 统计符合筛选条件、但因保护标记而跳过的邮件数
+// 统计失败只记录日志，不影响清理
+func (c *Cleaner) countProtectedFlags(conn *imapClient.PooledConn, ctx *cleanFolderContext, clientFilter bool) int {
+	criteria := c.buildMatchCriteria(ctx)
+	if clientFilter {
+		criteria = c.buildRelaxedCriteria(ctx)
+	}
+	query.And(criteria, anyFlagCriteria(ctx.protectFlags))
+
+	if err := c.throttle(); err != nil {
+		return 0
+	}
+	data, err := conn.Client().UIDSearch(criteria, nil).Wait()
+	if err != nil {
+		log.Printf("[WARN] [%s] 统计受保护的邮件失败: %v", ctx.folderName, err)
+		return 0
+	}
+	uids := data.AllUIDs()
+	if clientFilter && len(uids) > 0 {
+		if uids, err = c.filterByEnvelope(conn, ctx, uids); err != nil {
+			log.Printf("[WARN] [%s] 统计受保护的邮件失败: %v", ctx.folderName, err)
+			return 0
+		}
+	}
+	return len(uids)
+}
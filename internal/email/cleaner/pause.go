@@ -0,0 +1,109 @@
+package cleaner
+
+import (
+	"fmt"
+	"log"
+
+	imapClient "CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/model"
+)
+
+// Pause 暂停清理：各文件夹在当前批次结束后把连接归还连接池并等待继续，尚未开始的文件夹不再获取连接
+// 已删除的进度保留在内存和检查点中，继续后从原来的位置接着删除
+func (c *Cleaner) Pause() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resumeCh != nil {
+		return fmt.Errorf("清理任务已暂停")
+	}
+	c.resumeCh = make(chan struct{})
+	return nil
+}
+
+// Resume 继续暂停的清理
+func (c *Cleaner) Resume() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resumeCh == nil {
+		return fmt.Errorf("清理任务未暂停")
+	}
+	close(c.resumeCh)
+	c.resumeCh = nil
+	return nil
+}
+
+// Paused 是否已暂停
+func (c *Cleaner) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.resumeCh != nil
+}
+
+// waitResume 暂停时阻塞到继续或取消，返回是否应继续执行
+func (c *Cleaner) waitResume(ctx *cleanFolderContext, stat *model.FolderCleanStat) bool {
+	for {
+		c.mu.Lock()
+		ch := c.resumeCh
+		c.mu.Unlock()
+		if ch == nil {
+			return c.ctx.Err() == nil
+		}
+
+		log.Printf("[DEBUG] [%s] 已暂停，等待继续", ctx.folderName)
+		c.sendProgress(&model.CleanProgress{
+			CurrentFolder: ctx.folderName,
+			FolderIndex:   ctx.folderIdx + 1,
+			TotalFolders:  ctx.totalFolders,
+			DeletedCount:  stat.DeletedCount,
+			MatchedCount:  stat.MatchedCount,
+			Status:        "paused",
+			Message:       fmt.Sprintf("文件夹 %s: 已暂停，已%s %d 封", ctx.folderName, ctx.actionName(), stat.DeletedCount),
+		})
+
+		select {
+		case <-ch:
+		case <-c.ctx.Done():
+			return false
+		}
+	}
+}
+
+// pauseBetweenBatches 批次之间检查暂停：暂停时归还连接，继续后重新获取连接并选择文件夹
+// 未暂停时原样返回 conn；取消时返回 nil 和 nil 错误
+func (c *Cleaner) pauseBetweenBatches(conn *imapClient.PooledConn, ctx *cleanFolderContext, stat *model.FolderCleanStat) (*imapClient.PooledConn, error) {
+	if !c.Paused() {
+		return conn, nil
+	}
+
+	conn.Release()
+	if !c.waitResume(ctx, stat) {
+		return nil, nil
+	}
+
+	conn, err := c.getConnection()
+	if err != nil {
+		return nil, err
+	}
+	mbox, err := conn.Client().Select(ctx.folderName, nil).Wait()
+	if err != nil {
+		conn.MarkBad()
+		return nil, fmt.Errorf("重新选择文件夹失败: %w", err)
+	}
+	// 暂停期间 UIDVALIDITY 变化时剩余的 UID 已失效，不能继续删除
+	if mbox.UIDValidity != ctx.uidValidity {
+		conn.Release()
+		return nil, fmt.Errorf("暂停期间 UIDVALIDITY 已变化 (%d -> %d)，请重新清理该文件夹", ctx.uidValidity, mbox.UIDValidity)
+	}
+
+	log.Printf("[DEBUG] [%s] 继续清理", ctx.folderName)
+	c.sendProgress(&model.CleanProgress{
+		CurrentFolder: ctx.folderName,
+		FolderIndex:   ctx.folderIdx + 1,
+		TotalFolders:  ctx.totalFolders,
+		DeletedCount:  stat.DeletedCount,
+		MatchedCount:  stat.MatchedCount,
+		Status:        "running",
+		Message:       fmt.Sprintf("文件夹 %s: 继续%s", ctx.folderName, ctx.actionName()),
+	})
+	return conn, nil
+}
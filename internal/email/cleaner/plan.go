@@ -0,0 +1,121 @@
+package cleaner
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+
+	imapClient "CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/model"
+)
+
+// SetPlan 按清理计划执行：只删除计划中的 UID，不再重新搜索
+// 任一文件夹的 UIDVALIDITY 与预览时不一致，或计划已过期，清理会被拒绝
+func (c *Cleaner) SetPlan(plan *model.CleanPlan) {
+	c.plan = make(map[string]*model.CleanPlanFolder, len(plan.Folders))
+	for _, f := range plan.Folders {
+		c.plan[f.Folder] = f
+	}
+	c.planExpiresAt = plan.ExpiresAt
+}
+
+// PlanFolders 获取预览模式下各文件夹符合条件的 UID（Clean 结束后调用），用于生成清理计划
+func (c *Cleaner) PlanFolders() []*model.CleanPlanFolder {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.planFolders
+}
+
+// addPlanFolder 记录预览文件夹的 UID 集合（各文件夹并发调用）
+func (c *Cleaner) addPlanFolder(ctx *cleanFolderContext, uids []imap.UID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.planFolders = append(c.planFolders, &model.CleanPlanFolder{
+		Folder:      ctx.folderName,
+		UIDValidity: ctx.uidValidity,
+		UIDs:        formatUIDs(uids),
+		Count:       len(uids),
+	})
+}
+
+// verifyPlan 执行前校验计划：未过期，且所有文件夹的 UIDVALIDITY 与预览时一致
+func (c *Cleaner) verifyPlan() error {
+	if time.Now().After(c.planExpiresAt) {
+		return fmt.Errorf("清理计划已过期，请重新预览")
+	}
+
+	conn, err := c.getConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	for folderName, f := range c.plan {
+		data, err := conn.Client().Status(folderName, &imap.StatusOptions{UIDValidity: true}).Wait()
+		if err != nil {
+			conn.MarkBad()
+			return fmt.Errorf("获取文件夹 %s 状态失败: %w", folderName, err)
+		}
+		if data.UIDValidity != f.UIDValidity {
+			return fmt.Errorf("文件夹 %s 的 UIDVALIDITY 已变化 (%d -> %d)，清理计划已失效，请重新预览",
+				folderName, f.UIDValidity, data.UIDValidity)
+		}
+	}
+	return nil
+}
+
+// planUIDs 返回计划中该文件夹仍存在、且未带保护标记的 UID，以及因保护标记跳过的邮件数
+// 选中文件夹后再次校验 UIDVALIDITY，防止校验之后文件夹被重建
+func (c *Cleaner) planUIDs(conn *imapClient.PooledConn, ctx *cleanFolderContext) ([]imap.UID, int, *imapClient.PooledConn, error) {
+	f := c.plan[ctx.folderName]
+	if f == nil {
+		return nil, 0, conn, nil
+	}
+	if ctx.uidValidity != f.UIDValidity {
+		return nil, 0, conn, fmt.Errorf("UIDVALIDITY 已变化 (%d -> %d)，清理计划已失效，请重新预览", f.UIDValidity, ctx.uidValidity)
+	}
+
+	planned, err := parseUIDs(f.UIDs)
+	if err != nil || len(planned) == 0 {
+		return nil, 0, conn, err
+	}
+
+	// 预览之后被其他客户端删除的邮件不再计入；预览之后加了保护标记的邮件同样跳过
+	uidSet := imap.UIDSet{}
+	uidSet.AddNum(planned...)
+	var existing, allowed []imap.UID
+	result, err := c.retryWithReconnect(conn, ctx.folderName, func(cli *imapclient.Client) error {
+		if err := c.throttle(); err != nil {
+			return err
+		}
+		data, err := cli.UIDSearch(&imap.SearchCriteria{UID: []imap.UIDSet{uidSet}}, nil).Wait()
+		if err != nil {
+			return err
+		}
+		existing = data.AllUIDs()
+		allowed = existing
+		if len(ctx.protectFlags) > 0 && len(existing) > 0 {
+			if err := c.throttle(); err != nil {
+				return err
+			}
+			data, err := cli.UIDSearch(&imap.SearchCriteria{UID: []imap.UIDSet{uidSet}, NotFlag: ctx.protectFlags}, nil).Wait()
+			if err != nil {
+				return err
+			}
+			allowed = data.AllUIDs()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, conn, fmt.Errorf("查询计划中的邮件失败: %w", err)
+	}
+
+	uids := intersectUIDs(planned, allowed)
+	if missing := len(planned) - len(existing); missing > 0 {
+		log.Printf("[DEBUG] [%s] 计划中有 %d 封邮件已不存在", ctx.folderName, missing)
+	}
+	return uids, len(existing) - len(allowed), result.conn, nil
+}
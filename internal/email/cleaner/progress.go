@@ -0,0 +1,217 @@
+package cleaner
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"CleanMyEmail/internal/model"
+)
+
+const (
+	progressInterval = 500 * time.Millisecond // 进度快照的发布间隔
+	rateSmoothing    = 0.3                    // 处理速度的平滑系数，越大越接近最近一段时间的速度
+	cancelSendWait   = 5 * time.Second        // 清理取消后等待消费者读取快照的最长时间
+)
+
+// progressAggregator 合并各文件夹并发上报的进度，按固定间隔发布包含全部文件夹的一致快照
+// 发布时阻塞发送：消费者处理较慢时中间状态被合并而不是丢弃，文件夹结束和清理结束的状态总会送达
+// 调用方必须持续读取进度通道直到其关闭；清理取消后消费者不再读取时，发送最多等待 cancelSendWait
+type progressAggregator struct {
+	ctx       context.Context // 清理的上下文，取消后发送不再无限等待
+	out       chan *model.CleanProgress
+	accountID int64
+	startTime time.Time
+
+	mu      sync.Mutex
+	folders map[string]*model.FolderProgress
+	latest  *model.CleanProgress // 最近一次上报，提供当前文件夹、批次和消息
+	dirty   bool
+
+	// 处理速度按发布间隔采样并平滑
+	rate        float64
+	lastDeleted int
+	lastSample  time.Time
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newProgressAggregator 创建进度聚合器并开始按间隔发布，所有文件夹初始为 pending
+func newProgressAggregator(ctx context.Context, out chan *model.CleanProgress, req *model.CleanRequest, startTime time.Time) *progressAggregator {
+	a := &progressAggregator{
+		ctx:        ctx,
+		out:        out,
+		accountID:  req.AccountID,
+		startTime:  startTime,
+		folders:    make(map[string]*model.FolderProgress, len(req.Folders)),
+		lastSample: startTime,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	for i, folder := range req.Folders {
+		if _, ok := a.folders[folder]; !ok {
+			a.folders[folder] = &model.FolderProgress{Folder: folder, FolderIndex: i + 1, Status: "pending"}
+		}
+	}
+	go a.run()
+	return a
+}
+
+// run 按固定间隔发布有变化的快照
+func (a *progressAggregator) run() {
+	defer close(a.done)
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if snapshot := a.snapshot(); snapshot != nil {
+				a.send(snapshot)
+			}
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// update 合并一次进度上报（各文件夹并发调用，不阻塞）
+func (a *progressAggregator) update(progress *model.CleanProgress) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.latest = progress
+	a.dirty = true
+
+	f := a.folders[progress.CurrentFolder]
+	if f == nil || isTerminalStatus(f.Status) {
+		return
+	}
+	f.Status = progress.Status
+	// 部分上报（如筛选阶段的提示）不带计数，计数只增不减
+	f.MatchedCount = max(f.MatchedCount, progress.MatchedCount)
+	f.DeletedCount = max(f.DeletedCount, progress.DeletedCount)
+	f.ProcessedBytes = max(f.ProcessedBytes, progress.ProcessedBytes)
+	if progress.CurrentBatch > 0 {
+		f.CurrentBatch = progress.CurrentBatch
+		f.TotalBatches = progress.TotalBatches
+	}
+}
+
+// finishFolder 记录文件夹的最终统计，此后该文件夹的上报不再生效
+func (a *progressAggregator) finishFolder(stat model.FolderCleanStat) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f := a.folders[stat.Folder]
+	if f == nil {
+		return
+	}
+	f.Status = stat.Status
+	f.MatchedCount = stat.MatchedCount
+	f.DeletedCount = stat.DeletedCount
+	f.ProcessedBytes = stat.ProcessedBytes
+	f.Error = stat.Error
+	a.dirty = true
+}
+
+// finish 停止定时发布，发送最终快照后关闭进度通道（可重复调用，只有第一次生效）
+// final 为 nil 时（清理出错提前返回）只发送尚未发布的变化
+func (a *progressAggregator) finish(final *model.CleanProgress) {
+	a.closeOnce.Do(func() {
+		close(a.stop)
+		<-a.done
+
+		a.mu.Lock()
+		if final != nil {
+			a.latest = final
+			a.dirty = true
+			// 未开始或未结束的文件夹沿用整体状态（如取消）
+			for _, f := range a.folders {
+				if !isTerminalStatus(f.Status) {
+					f.Status = final.Status
+				}
+			}
+		}
+		a.mu.Unlock()
+
+		if snapshot := a.snapshot(); snapshot != nil {
+			a.send(snapshot)
+		}
+		close(a.out)
+	})
+}
+
+// send 阻塞发布快照；清理已取消时最多再等待 cancelSendWait，消费者不再读取时放弃，避免 Clean 无法结束
+func (a *progressAggregator) send(snapshot *model.CleanProgress) {
+	select {
+	case a.out <- snapshot:
+		return
+	case <-a.ctx.Done():
+	}
+	timer := time.NewTimer(cancelSendWait)
+	defer timer.Stop()
+	select {
+	case a.out <- snapshot:
+	case <-timer.C:
+	}
+}
+
+// snapshot 生成当前快照，自上次发布以来没有变化时返回 nil
+func (a *progressAggregator) snapshot() *model.CleanProgress {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.dirty || a.latest == nil {
+		return nil
+	}
+	a.dirty = false
+
+	snapshot := *a.latest
+	snapshot.AccountID = a.accountID
+	snapshot.TotalFolders = len(a.folders)
+	snapshot.Folders = make([]model.FolderProgress, 0, len(a.folders))
+	snapshot.DeletedCount, snapshot.MatchedCount, snapshot.ProcessedBytes = 0, 0, 0
+	remaining := 0
+	for _, f := range a.folders {
+		snapshot.Folders = append(snapshot.Folders, *f)
+		snapshot.DeletedCount += f.DeletedCount
+		snapshot.MatchedCount += f.MatchedCount
+		snapshot.ProcessedBytes += f.ProcessedBytes
+		if !isTerminalStatus(f.Status) {
+			remaining += max(f.MatchedCount-f.DeletedCount, 0)
+		}
+	}
+	sort.Slice(snapshot.Folders, func(i, j int) bool {
+		return snapshot.Folders[i].FolderIndex < snapshot.Folders[j].FolderIndex
+	})
+
+	now := time.Now()
+	if snapshot.ElapsedSeconds == 0 {
+		snapshot.ElapsedSeconds = now.Sub(a.startTime).Seconds()
+	}
+	if elapsed := now.Sub(a.lastSample).Seconds(); elapsed > 0 {
+		current := float64(snapshot.DeletedCount-a.lastDeleted) / elapsed
+		if a.lastDeleted == 0 {
+			a.rate = current
+		} else {
+			a.rate = rateSmoothing*current + (1-rateSmoothing)*a.rate
+		}
+		a.lastDeleted = snapshot.DeletedCount
+		a.lastSample = now
+	}
+	snapshot.MessagesPerSecond = a.rate
+	// 只统计已完成搜索的文件夹，尚未开始的文件夹匹配数未知；暂停时不估算
+	if a.rate > 0 && remaining > 0 && snapshot.Status == "running" {
+		snapshot.ETASeconds = float64(remaining) / a.rate
+	}
+	return &snapshot
+}
+
+// isTerminalStatus 文件夹或清理任务是否已结束
+func isTerminalStatus(status string) bool {
+	switch status {
+	case "completed", "skipped", "failed", "cancelled":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package cleaner
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+
+	"CleanMyEmail/internal/email/attachment"
+	imapClient "CleanMyEmail/internal/email/imap"
+)
+
+// stripBatch 移除一批邮件的附件：附件保存到本地，去掉附件的邮件以原标记和日期重新上传到同一文件夹
+// 返回已上传新邮件、可以删除原邮件的 UID 以及释放的字节数；没有附件的邮件保持不变
+// 逐封获取原文，避免整批邮件同时载入内存；已上传的邮件记录在 ctx.stripped 和检查点中，重试或继续中断的清理时不会重复上传
+func (c *Cleaner) stripBatch(client *imapclient.Client, ctx *cleanFolderContext, uids []imap.UID) ([]imap.UID, int64, error) {
+	bodySection := &imap.FetchItemBodySection{Peek: true}
+	for _, uid := range uids {
+		if _, ok := ctx.stripped[uid]; ok {
+			continue
+		}
+		if err := c.throttle(); err != nil {
+			return nil, 0, err
+		}
+
+		fetchCmd := client.Fetch(imap.UIDSetNum(uid), &imap.FetchOptions{
+			UID:          true,
+			Flags:        true,
+			InternalDate: true,
+			BodySection:  []*imap.FetchItemBodySection{bodySection},
+		})
+		var buf *imapclient.FetchMessageBuffer
+		if msg := fetchCmd.Next(); msg != nil {
+			var err error
+			if buf, err = msg.Collect(); err != nil {
+				fetchCmd.Close()
+				return nil, 0, fmt.Errorf("获取邮件原文失败: %w", err)
+			}
+		}
+		if err := fetchCmd.Close(); err != nil {
+			return nil, 0, fmt.Errorf("获取邮件原文失败: %w", err)
+		}
+		if buf == nil || buf.UID == 0 {
+			continue // 邮件已被其他客户端删除
+		}
+		raw := buf.FindBodySection(bodySection)
+		if raw == nil {
+			continue
+		}
+
+		rebuilt, parts, err := attachment.Strip(raw, ctx.req.MinAttachmentSize,
+			attachment.FileSaver(c.attachmentDir, ctx.folderName, uint32(uid)))
+		if err != nil {
+			return nil, 0, err
+		}
+		if rebuilt == nil {
+			ctx.stripped[uid] = -1 // 没有可移除的附件，不删除原邮件
+			continue
+		}
+
+		options := &imap.AppendOptions{Time: buf.InternalDate}
+		for _, flag := range buf.Flags {
+			if flag == imap.FlagDeleted || flag == `\Recent` {
+				continue
+			}
+			options.Flags = append(options.Flags, flag)
+		}
+		if err := c.throttle(); err != nil {
+			return nil, 0, err
+		}
+		if err := imapClient.AppendMessage(client, ctx.folderName, rebuilt, options); err != nil {
+			return nil, 0, fmt.Errorf("上传移除附件后的邮件失败: %w", err)
+		}
+		ctx.stripped[uid] = int64(len(raw) - len(rebuilt))
+		c.storeCheckpoint(ctx)
+		log.Printf("[DEBUG] [%s] UID %d 移除 %d 个附件，释放 %d 字节", ctx.folderName, uid, len(parts), ctx.stripped[uid])
+	}
+
+	var strippedUIDs []imap.UID
+	var reclaimed int64
+	for _, uid := range uids {
+		if saved, ok := ctx.stripped[uid]; ok && saved >= 0 {
+			strippedUIDs = append(strippedUIDs, uid)
+			reclaimed += saved
+		}
+	}
+	if skipped := len(uids) - len(strippedUIDs); skipped > 0 {
+		log.Printf("[DEBUG] [%s] %d 封邮件没有可移除的附件，保持不变", ctx.folderName, skipped)
+	}
+	return strippedUIDs, reclaimed, nil
+}
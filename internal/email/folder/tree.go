@@ -43,6 +43,7 @@ func buildFlatTree(folders []*model.MailFolder) []*model.FolderTreeNode {
 			Label:        f.Name,
 			FullPath:     f.FullPath,
 			MessageCount: f.MessageCount,
+			Size:         f.Size,
 			IsLeaf:       true,
 			Disabled:     !f.IsSelectable,
 		})
@@ -72,6 +73,7 @@ func buildHierarchicalTree(folders []*model.MailFolder, delimiter string) []*mod
 			Label:        parts[len(parts)-1],
 			FullPath:     f.FullPath,
 			MessageCount: f.MessageCount,
+			Size:         f.Size,
 			IsLeaf:       true,
 			Disabled:     false, // 允许所有文件夹被勾选
 		}
@@ -25,6 +25,8 @@ type ConnectConfig struct {
 	// TokenRefresher 用于在 token 过期时刷新，返回新的 access token
 	// 如果为 nil，则不支持自动刷新
 	TokenRefresher func() (string, error)
+	// RateLimit 命令限速，连接池管理器按服务器主机共享限速器（直接创建的连接池需自行 SetLimiter）
+	RateLimit model.RateLimit
 }
 
 // Connect 连接到IMAP服务器（带重试）
@@ -214,6 +216,11 @@ func SupportsListStatus(client *imapclient.Client) bool {
 	return client.Caps().Has(imap.CapListStatus)
 }
 
+// SupportsStatusSize 检查服务器是否支持 STATUS=SIZE 扩展（RFC 8438，IMAP4rev2 已包含）
+func SupportsStatusSize(client *imapclient.Client) bool {
+	return client.Caps().Has(imap.CapStatusSize)
+}
+
 // ListMailboxes 列出所有邮箱文件夹
 func ListMailboxes(client *imapclient.Client) ([]*model.MailFolder, error) {
 	startTime := time.Now()
@@ -222,16 +229,18 @@ func ListMailboxes(client *imapclient.Client) ([]*model.MailFolder, error) {
 	// 检查服务器是否支持 LIST-STATUS 扩展
 	caps := client.Caps()
 	supportsListStatus := caps.Has(imap.CapListStatus)
-	log.Printf("[DEBUG] 服务器支持 LIST-STATUS: %v", supportsListStatus)
+	supportsStatusSize := caps.Has(imap.CapStatusSize)
+	log.Printf("[DEBUG] 服务器支持 LIST-STATUS: %v, STATUS=SIZE: %v", supportsListStatus, supportsStatusSize)
 
 	// 构建 LIST 命令选项
 	var listOpts *imap.ListOptions
 	if supportsListStatus {
-		// 如果支持 LIST-STATUS，请求邮件数量
+		// 如果支持 LIST-STATUS，请求邮件数量（支持 STATUS=SIZE 时同时请求占用空间）
 		listOpts = &imap.ListOptions{
 			ReturnStatus: &imap.StatusOptions{
 				NumMessages: true,
 				NumUnseen:   true,
+				Size:        supportsStatusSize,
 			},
 		}
 		log.Printf("[DEBUG] 发送 LIST-STATUS 命令...")
@@ -277,6 +286,9 @@ func ListMailboxes(client *imapclient.Client) ([]*model.MailFolder, error) {
 			if mbox.Status.NumUnseen != nil {
 				folder.UnseenCount = *mbox.Status.NumUnseen
 			}
+			if mbox.Status.Size != nil {
+				folder.Size = *mbox.Status.Size
+			}
 		}
 
 		// 调试：记录 LIST-STATUS 返回的数量
@@ -300,17 +312,32 @@ func ListMailboxes(client *imapclient.Client) ([]*model.MailFolder, error) {
 	return folders, nil
 }
 
+// FindSpecialUseFolder 根据 SPECIAL-USE 属性（如 \Trash）查找文件夹
+func FindSpecialUseFolder(folders []*model.MailFolder, attr imap.MailboxAttr) *model.MailFolder {
+	for _, folder := range folders {
+		for _, a := range folder.Attributes {
+			if strings.EqualFold(a, string(attr)) {
+				return folder
+			}
+		}
+	}
+	return nil
+}
+
 // FolderStatusUpdate 文件夹状态更新
 type FolderStatusUpdate struct {
 	FolderPath   string `json:"folderPath"`
 	MessageCount uint32 `json:"messageCount"`
 	UnseenCount  uint32 `json:"unseenCount"`
+	Size         int64  `json:"size"` // 占用空间（字节），-1 表示未获取
 }
 
 // FetchFolderStatus 异步获取文件夹邮件数量，通过回调返回
 // 策略：先用 STATUS 命令（快速），如果返回 0 则用 EXAMINE 回退（更可靠）
+// 服务器支持 STATUS=SIZE 时同时返回占用空间，否则 Size 为 -1，可再调用 FetchFolderSizes 计算
 func FetchFolderStatus(client *imapclient.Client, folders []*model.MailFolder, onUpdate func(FolderStatusUpdate)) {
 	log.Printf("[DEBUG] 开始异步获取 %d 个文件夹的邮件数量...", len(folders))
+	supportsStatusSize := SupportsStatusSize(client)
 	successCount := 0
 	fallbackCount := 0
 	for i, folder := range folders {
@@ -319,15 +346,20 @@ func FetchFolderStatus(client *imapclient.Client, folders []*model.MailFolder, o
 		}
 
 		var messageCount uint32 = 0
+		var size int64 = -1
 
 		// 先尝试 STATUS 命令（快速）
 		statusCmd := client.Status(folder.FullPath, &imap.StatusOptions{
 			NumMessages: true,
+			Size:        supportsStatusSize,
 		})
 		statusData, err := statusCmd.Wait()
 		if err == nil && statusData.NumMessages != nil {
 			messageCount = *statusData.NumMessages
 		}
+		if err == nil && statusData.Size != nil {
+			size = *statusData.Size
+		}
 
 		// 如果 STATUS 返回 0，使用 EXAMINE 回退
 		if messageCount == 0 {
@@ -346,6 +378,7 @@ func FetchFolderStatus(client *imapclient.Client, folders []*model.MailFolder, o
 		update := FolderStatusUpdate{
 			FolderPath:   folder.FullPath,
 			MessageCount: messageCount,
+			Size:         size,
 		}
 		onUpdate(update)
 		successCount++
@@ -357,3 +390,111 @@ func FetchFolderStatus(client *imapclient.Client, folders []*model.MailFolder, o
 	}
 	log.Printf("[DEBUG] 文件夹状态获取完成，成功 %d 个，EXAMINE回退 %d 个", successCount, fallbackCount)
 }
+
+// FetchFolderSizes 服务器不支持 STATUS=SIZE 时，逐个文件夹获取所有邮件的 RFC822.SIZE 累加得到占用空间
+// 邮件较多时较慢，通过回调逐个返回（同时返回邮件数量）
+func FetchFolderSizes(client *imapclient.Client, folders []*model.MailFolder, onUpdate func(FolderStatusUpdate)) {
+	log.Printf("[DEBUG] 开始计算 %d 个文件夹的占用空间...", len(folders))
+	for _, folder := range folders {
+		if !folder.IsSelectable {
+			continue
+		}
+		count, size, err := FolderSize(client, folder.FullPath)
+		if err != nil {
+			log.Printf("[DEBUG] 计算文件夹 %s 占用空间失败: %v", folder.FullPath, err)
+			continue
+		}
+		onUpdate(FolderStatusUpdate{
+			FolderPath:   folder.FullPath,
+			MessageCount: count,
+			Size:         size,
+		})
+	}
+	log.Printf("[DEBUG] 文件夹占用空间计算完成")
+}
+
+// AppendMessage 执行单次 APPEND，上传完整的邮件原文
+func AppendMessage(client *imapclient.Client, folder string, body []byte, options *imap.AppendOptions) error {
+	appendCmd := client.Append(folder, int64(len(body)), options)
+	if _, err := appendCmd.Write(body); err != nil {
+		appendCmd.Close()
+		return err
+	}
+	if err := appendCmd.Close(); err != nil {
+		return err
+	}
+	_, err := appendCmd.Wait()
+	return err
+}
+
+// FolderSize 以只读方式选择文件夹，获取所有邮件的 RFC822.SIZE 并累加，返回邮件数量和占用空间
+func FolderSize(client *imapclient.Client, folderPath string) (uint32, int64, error) {
+	selectData, err := client.Select(folderPath, &imap.SelectOptions{ReadOnly: true}).Wait()
+	if err != nil {
+		return 0, 0, err
+	}
+	if selectData.NumMessages == 0 {
+		return 0, 0, nil
+	}
+
+	var size int64
+	seqSet := imap.SeqSet{}
+	seqSet.AddRange(1, 0) // 1:*
+	fetchCmd := client.Fetch(seqSet, &imap.FetchOptions{RFC822Size: true})
+	for msg := fetchCmd.Next(); msg != nil; msg = fetchCmd.Next() {
+		for item := msg.Next(); item != nil; item = msg.Next() {
+			if data, ok := item.(imapclient.FetchItemDataRFC822Size); ok {
+				size += data.Size
+			}
+		}
+	}
+	if err := fetchCmd.Close(); err != nil {
+		return 0, 0, err
+	}
+	return selectData.NumMessages, size, nil
+}
+
+// CollectPreviewMessage 读取一封邮件的 FETCH 数据，UID 缺失时返回 nil
+func CollectPreviewMessage(msg *imapclient.FetchMessageData, folderName string) *model.PreviewMessage {
+	preview := &model.PreviewMessage{Folder: folderName}
+	var internalDate time.Time
+	for item := msg.Next(); item != nil; item = msg.Next() {
+		switch data := item.(type) {
+		case imapclient.FetchItemDataUID:
+			preview.UID = uint32(data.UID)
+		case imapclient.FetchItemDataEnvelope:
+			if data.Envelope == nil {
+				continue
+			}
+			preview.Subject = data.Envelope.Subject
+			preview.Date = data.Envelope.Date
+			if len(data.Envelope.From) > 0 {
+				preview.From = FormatAddress(data.Envelope.From[0])
+			}
+		case imapclient.FetchItemDataRFC822Size:
+			preview.Size = data.Size
+		case imapclient.FetchItemDataFlags:
+			preview.Flags = make([]string, len(data.Flags))
+			for i, flag := range data.Flags {
+				preview.Flags[i] = string(flag)
+			}
+		case imapclient.FetchItemDataInternalDate:
+			internalDate = data.Time
+		}
+	}
+	if preview.UID == 0 {
+		return nil
+	}
+	if preview.Date.IsZero() {
+		preview.Date = internalDate
+	}
+	return preview
+}
+
+// FormatAddress 格式化邮件地址，如 "Name <user@example.com>"
+func FormatAddress(addr imap.Address) string {
+	if addr.Name == "" {
+		return addr.Addr()
+	}
+	return fmt.Sprintf("%s <%s>", addr.Name, addr.Addr())
+}
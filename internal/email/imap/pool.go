@@ -39,6 +39,7 @@ type ConnectionPool struct {
 	maxSize     int
 	idleTimeout time.Duration
 	logPrefix   string        // 日志前缀，包含账号信息
+	limiter     *RateLimiter  // 命令限速器（同一主机的连接池共享），为 nil 时不限速
 
 	mu          sync.Mutex
 	cond        *sync.Cond    // 条件变量，用于等待连接释放
@@ -270,6 +271,20 @@ func (p *ConnectionPool) Put(conn *PooledConn) {
 	p.cond.Signal()
 }
 
+// SetLimiter 设置命令限速器
+func (p *ConnectionPool) SetLimiter(limiter *RateLimiter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.limiter = limiter
+}
+
+// Limiter 获取命令限速器，未设置时返回 nil
+func (p *ConnectionPool) Limiter() *RateLimiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.limiter
+}
+
 // Close 关闭连接池
 func (p *ConnectionPool) Close() {
 	p.mu.Lock()
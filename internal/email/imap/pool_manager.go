@@ -5,6 +5,8 @@ import (
 	"log"
 	"sync"
 	"time"
+
+	"CleanMyEmail/internal/model"
 )
 
 const (
@@ -13,9 +15,11 @@ const (
 )
 
 // PoolManager 连接池管理器，为每个账号维护一个连接池
+// 同一服务器主机的连接池共享一个命令限速器，避免多个账号同时清理时触发服务器限流
 type PoolManager struct {
-	mu    sync.RWMutex
-	pools map[int64]*managedPool // key: accountID
+	mu       sync.RWMutex
+	pools    map[int64]*managedPool  // key: accountID
+	limiters map[string]*RateLimiter // key: 服务器主机名
 
 	stopCleanup chan struct{}
 	cleanupDone chan struct{}
@@ -32,6 +36,7 @@ type managedPool struct {
 func NewPoolManager() *PoolManager {
 	pm := &PoolManager{
 		pools:       make(map[int64]*managedPool),
+		limiters:    make(map[string]*RateLimiter),
 		stopCleanup: make(chan struct{}),
 		cleanupDone: make(chan struct{}),
 	}
@@ -54,7 +59,9 @@ func (pm *PoolManager) GetPool(accountID int64, config *ConnectConfig, opts *Poo
 			// 更新可能变化的字段（如 AccessToken、TokenRefresher）
 			mp.config.AccessToken = config.AccessToken
 			mp.config.TokenRefresher = config.TokenRefresher
+			mp.config.RateLimit = config.RateLimit
 			mp.pool.UpdateConfig(config)
+			mp.pool.SetLimiter(pm.limiterLocked(config))
 
 			// 检查是否需要扩容
 			if opts != nil && opts.MaxSize > 0 {
@@ -82,10 +89,48 @@ func (pm *PoolManager) GetPool(accountID int64, config *ConnectConfig, opts *Poo
 		config:     config,
 		lastAccess: time.Now(),
 	}
+	pool.SetLimiter(pm.limiterLocked(config))
 	log.Printf("[DEBUG] %s 创建新连接池 (大小: %d)", logPrefix, pool.MaxSize())
 	return pool
 }
 
+// limiterLocked 获取或创建服务器主机的限速器，并按该主机的限速更新，调用方需持有 mu（且已登记 config 所属的连接池）
+// 限速器不随连接池清理，退避状态在连接池重建后仍然有效
+func (pm *PoolManager) limiterLocked(config *ConnectConfig) *RateLimiter {
+	key := limiterKey(config.Server)
+	limit := pm.hostLimitLocked(key)
+	limiter, ok := pm.limiters[key]
+	if !ok {
+		limiter = NewRateLimiter(config.Server, limit)
+		pm.limiters[key] = limiter
+		return limiter
+	}
+	limiter.SetLimit(limit)
+	return limiter
+}
+
+// hostLimitLocked 同一主机所有连接池中最严格的限速
+// 限速器由同一主机的所有账号共享，不能由最后连接的账号的设置决定
+func (pm *PoolManager) hostLimitLocked(key string) model.RateLimit {
+	var limit model.RateLimit
+	for _, mp := range pm.pools {
+		if limiterKey(mp.config.Server) != key {
+			continue
+		}
+		l := mp.config.RateLimit
+		if l.CommandsPerSecond <= 0 {
+			continue
+		}
+		if limit.CommandsPerSecond <= 0 || l.CommandsPerSecond < limit.CommandsPerSecond {
+			limit.CommandsPerSecond = l.CommandsPerSecond
+		}
+		if limit.Burst <= 0 || l.Burst < limit.Burst {
+			limit.Burst = l.Burst
+		}
+	}
+	return limit
+}
+
 // ClosePool 关闭指定账号的连接池
 func (pm *PoolManager) ClosePool(accountID int64) {
 	pm.mu.Lock()
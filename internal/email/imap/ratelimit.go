@@ -0,0 +1,160 @@
+package imap
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+
+	"CleanMyEmail/internal/model"
+)
+
+const (
+	minBackoff        = 5 * time.Second  // 服务器限流后的首次退避时间
+	maxBackoff        = 2 * time.Minute  // 最长退避时间
+	backoffResetAfter = 10 * time.Minute // 超过该时间没有再被限流时，退避时间恢复为初始值
+)
+
+// RateLimiter IMAP 命令限速器（令牌桶），由连接池管理器按服务器主机共享
+// 服务器返回限流响应时暂停所有命令一段时间，连续限流时退避时间加倍
+type RateLimiter struct {
+	host string
+
+	mu           sync.Mutex
+	rate         float64 // 每秒补充的令牌数，0 表示不限速
+	burst        float64
+	tokens       float64
+	last         time.Time // 上次补充令牌的时间
+	pausedUntil  time.Time // 限流退避的截止时间
+	backoff      time.Duration
+	lastThrottle time.Time
+}
+
+// NewRateLimiter 创建限速器，server 用于日志
+func NewRateLimiter(server string, limit model.RateLimit) *RateLimiter {
+	l := &RateLimiter{host: limiterKey(server)}
+	l.SetLimit(limit)
+	l.tokens = l.burst
+	return l
+}
+
+// SetLimit 更新限速配置（用户修改设置后生效），已有的令牌数不超过新的突发容量
+func (l *RateLimiter) SetLimit(limit model.RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = limit.CommandsPerSecond
+	l.burst = float64(limit.Burst)
+	if l.burst < 1 {
+		l.burst = 1
+	}
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// Wait 等待执行一条命令的令牌，限流退避期间一直等待到退避结束，ctx 取消时返回错误
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve 尝试取得一个令牌，返回需要等待的时间（0 表示已取得）
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+	if l.rate <= 0 {
+		return 0
+	}
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// Backoff 服务器返回限流响应后调用：暂停该主机的所有命令，返回退避时间
+// 退避期间再次限流时不重复加倍，避免多个连接同时报错时退避时间暴涨
+func (l *RateLimiter) Backoff() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+	switch {
+	case l.backoff == 0 || now.Sub(l.lastThrottle) > backoffResetAfter:
+		l.backoff = minBackoff
+	default:
+		l.backoff = min(l.backoff*2, maxBackoff)
+	}
+	l.lastThrottle = now
+	l.pausedUntil = now.Add(l.backoff)
+	// 退避结束后从空桶开始，避免立即以突发速率重新触发限流
+	l.tokens = 0
+	l.last = l.pausedUntil
+	log.Printf("[WARN] [%s] 服务器限流，暂停 %v 后继续", l.host, l.backoff)
+	return l.backoff
+}
+
+// IsThrottled 服务器是否因限流或暂时不可用拒绝了命令
+// 包括 [LIMIT]、[UNAVAILABLE] 响应码，以及 Gmail 的 [THROTTLED] 等只在文字中说明的限流
+// 只匹配明确的限流说明：超出配额（[OVERQUOTA]、"quota exceeded"）等退避后也无法成功的错误应直接失败
+func IsThrottled(err error) bool {
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) {
+		return false
+	}
+	switch imapErr.Code {
+	case imap.ResponseCodeLimit, imap.ResponseCodeUnavailable, "THROTTLED":
+		return true
+	case imap.ResponseCodeOverQuota:
+		return false
+	}
+	text := strings.ToLower(imapErr.Text)
+	for _, keyword := range []string{"throttl", "rate limit", "too many", "bandwidth limit"} {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// limiterKey 限速器的共享键：服务器主机名（不含端口，不区分大小写）
+func limiterKey(server string) string {
+	host := server
+	if h, _, err := net.SplitHostPort(server); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
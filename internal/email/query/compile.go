@@ -0,0 +1,173 @@
+package query
+
+import (
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+// headerKeys 邮件头字段对应的 IMAP 头名称
+var headerKeys = map[Field]string{
+	FieldFrom:    "From",
+	FieldTo:      "To",
+	FieldCc:      "Cc",
+	FieldSubject: "Subject",
+}
+
+// Compile 将表达式编译为 IMAP SEARCH 条件，相对时间以 now 为基准
+func Compile(node Node, now time.Time) *imap.SearchCriteria {
+	switch n := node.(type) {
+	case *AndNode:
+		criteria := &imap.SearchCriteria{}
+		for _, child := range n.Children {
+			And(criteria, Compile(child, now))
+		}
+		return criteria
+	case *OrNode:
+		result := Compile(n.Children[len(n.Children)-1], now)
+		for i := len(n.Children) - 2; i >= 0; i-- {
+			result = &imap.SearchCriteria{Or: [][2]imap.SearchCriteria{{*Compile(n.Children[i], now), *result}}}
+		}
+		return result
+	case *NotNode:
+		return &imap.SearchCriteria{Not: []imap.SearchCriteria{*Compile(n.Child, now)}}
+	case *TermNode:
+		return compileTerm(n, now)
+	default:
+		return &imap.SearchCriteria{}
+	}
+}
+
+// compileTerm 编译单个字段条件
+func compileTerm(n *TermNode, now time.Time) *imap.SearchCriteria {
+	criteria := &imap.SearchCriteria{}
+	switch n.Field {
+	case FieldFrom, FieldTo, FieldCc, FieldSubject:
+		criteria.Header = []imap.SearchCriteriaHeaderField{{Key: headerKeys[n.Field], Value: n.Value}}
+	case FieldLarger:
+		criteria.Larger = n.size
+	case FieldSmaller:
+		criteria.Smaller = n.size
+	case FieldOlder:
+		criteria.Before = n.cutoff(now)
+	case FieldNewer:
+		criteria.Since = n.cutoff(now)
+	case FieldBefore:
+		criteria.Before = n.date
+	case FieldSince:
+		criteria.Since = n.date
+	case FieldIs:
+		switch n.flag {
+		case "read":
+			criteria.Flag = []imap.Flag{imap.FlagSeen}
+		case "unread":
+			criteria.NotFlag = []imap.Flag{imap.FlagSeen}
+		case "flagged":
+			criteria.Flag = []imap.Flag{imap.FlagFlagged}
+		case "answered":
+			criteria.Flag = []imap.Flag{imap.FlagAnswered}
+		}
+	}
+	return criteria
+}
+
+// cutoff older/newer 的分界日期（IMAP 日期比较只精确到天）
+func (n *TermNode) cutoff(now time.Time) time.Time {
+	t := now.AddDate(-n.period[0], -n.period[1], -n.period[2])
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Relax 生成只包含服务端一定支持的条件的宽松版本，用于客户端回退：
+// 邮件头条件（服务端可能不支持）被放宽为“全部匹配”，结果是完整表达式的超集，
+// 再由 Match 在客户端精确过滤。返回 nil 表示无法在服务端缩小范围
+func Relax(node Node, now time.Time) *imap.SearchCriteria {
+	switch n := node.(type) {
+	case *AndNode:
+		var criteria *imap.SearchCriteria
+		for _, child := range n.Children {
+			if c := Relax(child, now); c != nil {
+				if criteria == nil {
+					criteria = &imap.SearchCriteria{}
+				}
+				And(criteria, c)
+			}
+		}
+		return criteria
+	case *OrNode:
+		// 用各分支的宽松条件组合 OR，任一分支无法缩小范围时整个 OR 也无法缩小
+		relaxed := make([]*imap.SearchCriteria, len(n.Children))
+		for i, child := range n.Children {
+			if relaxed[i] = Relax(child, now); relaxed[i] == nil {
+				return nil
+			}
+		}
+		result := relaxed[len(relaxed)-1]
+		for i := len(relaxed) - 2; i >= 0; i-- {
+			result = &imap.SearchCriteria{Or: [][2]imap.SearchCriteria{{*relaxed[i], *result}}}
+		}
+		return result
+	case *NotNode:
+		// 取反后宽松条件不再是超集，只有不含邮件头条件时才能交给服务端
+		if HasHeaderTerms(n.Child) {
+			return nil
+		}
+		return Compile(n, now)
+	case *TermNode:
+		if n.Field.isHeader() {
+			return nil
+		}
+		return compileTerm(n, now)
+	default:
+		return nil
+	}
+}
+
+// UseSentDate 将条件中按 INTERNALDATE 比较的 SINCE/BEFORE 改为按 Date: 邮件头比较的 SENTSINCE/SENTBEFORE（含 NOT、OR 内的条件）
+func UseSentDate(criteria *imap.SearchCriteria) {
+	if !criteria.Since.IsZero() {
+		criteria.SentSince, criteria.Since = criteria.Since, time.Time{}
+	}
+	if !criteria.Before.IsZero() {
+		criteria.SentBefore, criteria.Before = criteria.Before, time.Time{}
+	}
+	for i := range criteria.Not {
+		UseSentDate(&criteria.Not[i])
+	}
+	for i := range criteria.Or {
+		UseSentDate(&criteria.Or[i][0])
+		UseSentDate(&criteria.Or[i][1])
+	}
+}
+
+// HasHeaderTerms 表达式是否包含邮件头条件
+func HasHeaderTerms(node Node) bool {
+	switch n := node.(type) {
+	case *AndNode:
+		for _, child := range n.Children {
+			if HasHeaderTerms(child) {
+				return true
+			}
+		}
+	case *OrNode:
+		for _, child := range n.Children {
+			if HasHeaderTerms(child) {
+				return true
+			}
+		}
+	case *NotNode:
+		return HasHeaderTerms(n.Child)
+	case *TermNode:
+		return n.Field.isHeader()
+	}
+	return false
+}
+
+// And 将 other 合并到 criteria（AND）
+// imap.SearchCriteria.And 在 other.Smaller 为 0 时会清空已有的 Smaller，这里保留原值
+func And(criteria, other *imap.SearchCriteria) {
+	smaller := criteria.Smaller
+	criteria.And(other)
+	if other.Smaller == 0 {
+		criteria.Smaller = smaller
+	}
+}
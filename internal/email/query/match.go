@@ -0,0 +1,147 @@
+package query
+
+import (
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+// Message 客户端匹配所需的邮件属性（通过 FETCH ENVELOPE/RFC822.SIZE/FLAGS/INTERNALDATE 获取）
+type Message struct {
+	Envelope     *imap.Envelope
+	Size         int64
+	Flags        []imap.Flag
+	InternalDate time.Time
+	SentDate     bool // 日期条件按 Date: 邮件头（发送日期）匹配，对应 SENTBEFORE/SENTSINCE
+}
+
+// date 日期条件使用的邮件日期
+func (m *Message) date() time.Time {
+	if m.SentDate {
+		if m.Envelope == nil {
+			return time.Time{}
+		}
+		return m.Envelope.Date
+	}
+	return m.InternalDate
+}
+
+// FetchOptions 客户端匹配需要获取的邮件属性
+func FetchOptions() *imap.FetchOptions {
+	return &imap.FetchOptions{
+		Envelope:     true,
+		RFC822Size:   true,
+		Flags:        true,
+		InternalDate: true,
+	}
+}
+
+// Match 在客户端判断邮件是否满足表达式，语义与 Compile 生成的 IMAP SEARCH 一致
+func Match(node Node, msg *Message, now time.Time) bool {
+	switch n := node.(type) {
+	case *AndNode:
+		for _, child := range n.Children {
+			if !Match(child, msg, now) {
+				return false
+			}
+		}
+		return true
+	case *OrNode:
+		for _, child := range n.Children {
+			if Match(child, msg, now) {
+				return true
+			}
+		}
+		return false
+	case *NotNode:
+		return !Match(n.Child, msg, now)
+	case *TermNode:
+		return matchTerm(n, msg, now)
+	default:
+		return true
+	}
+}
+
+// matchTerm 匹配单个字段条件
+func matchTerm(n *TermNode, msg *Message, now time.Time) bool {
+	switch n.Field {
+	case FieldFrom, FieldTo, FieldCc:
+		if msg.Envelope == nil {
+			return false
+		}
+		var addrs []imap.Address
+		switch n.Field {
+		case FieldFrom:
+			addrs = msg.Envelope.From
+		case FieldTo:
+			addrs = msg.Envelope.To
+		default:
+			addrs = msg.Envelope.Cc
+		}
+		return matchAddress(addrs, n.Value)
+	case FieldSubject:
+		return msg.Envelope != nil && containsFold(msg.Envelope.Subject, n.Value)
+	case FieldLarger:
+		return msg.Size > n.size
+	case FieldSmaller:
+		return msg.Size < n.size
+	case FieldOlder:
+		return beforeDate(msg.date(), n.cutoff(now))
+	case FieldNewer:
+		return !msg.date().IsZero() && !beforeDate(msg.date(), n.cutoff(now))
+	case FieldBefore:
+		return beforeDate(msg.date(), n.date)
+	case FieldSince:
+		return !msg.date().IsZero() && !beforeDate(msg.date(), n.date)
+	case FieldIs:
+		switch n.flag {
+		case "read":
+			return hasFlag(msg.Flags, imap.FlagSeen)
+		case "unread":
+			return !hasFlag(msg.Flags, imap.FlagSeen)
+		case "flagged":
+			return hasFlag(msg.Flags, imap.FlagFlagged)
+		case "answered":
+			return hasFlag(msg.Flags, imap.FlagAnswered)
+		}
+	}
+	return false
+}
+
+// matchAddress 任一地址（含显示名）包含关键词
+func matchAddress(addrs []imap.Address, keyword string) bool {
+	for _, addr := range addrs {
+		if containsFold(addr.Addr(), keyword) || containsFold(addr.Name, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold 忽略大小写的子串匹配
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(strings.TrimSpace(substr)))
+}
+
+// hasFlag 是否包含标记
+func hasFlag(flags []imap.Flag, flag imap.Flag) bool {
+	for _, f := range flags {
+		if strings.EqualFold(string(f), string(flag)) {
+			return true
+		}
+	}
+	return false
+}
+
+// beforeDate 邮件日期是否早于 day 所在的日期，与 IMAP BEFORE/SENTBEFORE 一致：
+// 只比较日期，邮件日期取其自身时区（INTERNALDATE 或 Date: 头中的时区）的日期，不换算到本地时区
+// 没有日期的邮件（如缺少 Date: 头）不匹配任何日期条件
+func beforeDate(t, day time.Time) bool {
+	if t.IsZero() {
+		return false
+	}
+	ty, tm, td := t.Date()
+	dy, dm, dd := day.Date()
+	return time.Date(ty, tm, td, 0, 0, 0, 0, time.UTC).Before(time.Date(dy, dm, dd, 0, 0, 0, 0, time.UTC))
+}
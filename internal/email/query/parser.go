@@ -0,0 +1,395 @@
+// Package query 筛选表达式：解析如 `from:(a.com OR b.com) AND NOT subject:"invoice" AND larger:5M AND older:180d`
+// 的布尔表达式，编译为 IMAP SEARCH 条件，并提供服务端无法处理时的客户端匹配
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"CleanMyEmail/internal/model"
+)
+
+// Field 筛选字段
+type Field string
+
+const (
+	FieldFrom    Field = "from"    // 发件人（From 头，子串匹配）
+	FieldTo      Field = "to"      // 收件人（To 头）
+	FieldCc      Field = "cc"      // 抄送（Cc 头）
+	FieldSubject Field = "subject" // 主题
+	FieldLarger  Field = "larger"  // 大于指定大小，如 5M、100K
+	FieldSmaller Field = "smaller" // 小于指定大小
+	FieldOlder   Field = "older"   // 早于 N 天/周/月/年之前，如 180d、2y
+	FieldNewer   Field = "newer"   // N 天/周/月/年以内
+	FieldBefore  Field = "before"  // 早于指定日期 YYYY-MM-DD
+	FieldSince   Field = "since"   // 不早于指定日期 YYYY-MM-DD
+	FieldIs      Field = "is"      // 状态：read, unread, flagged, answered
+)
+
+// isHeader 字段是否为邮件头匹配（服务端可能不支持，需要客户端回退）
+func (f Field) isHeader() bool {
+	switch f {
+	case FieldFrom, FieldTo, FieldCc, FieldSubject:
+		return true
+	default:
+		return false
+	}
+}
+
+// Node 表达式语法树节点
+type Node interface {
+	String() string
+}
+
+// AndNode 所有子条件都满足
+type AndNode struct {
+	Children []Node
+}
+
+// OrNode 任一子条件满足
+type OrNode struct {
+	Children []Node
+}
+
+// NotNode 子条件不满足
+type NotNode struct {
+	Child Node
+}
+
+// TermNode 单个字段条件
+type TermNode struct {
+	Field Field
+	Value string
+
+	size   int64     // larger/smaller 的字节数
+	date   time.Time // before/since 的日期
+	period [3]int    // older/newer 的年、月、日偏移
+	flag   string    // is 的状态
+}
+
+func (n *AndNode) String() string { return joinNodes(n.Children, " AND ") }
+func (n *OrNode) String() string  { return joinNodes(n.Children, " OR ") }
+func (n *NotNode) String() string { return "NOT " + wrap(n.Child) }
+func (n *TermNode) String() string {
+	if strings.ContainsAny(n.Value, " ()\"") {
+		return string(n.Field) + ":" + strconv.Quote(n.Value)
+	}
+	return string(n.Field) + ":" + n.Value
+}
+
+// joinNodes 拼接子节点，复合子节点加括号
+func joinNodes(children []Node, sep string) string {
+	parts := make([]string, len(children))
+	for i, child := range children {
+		parts[i] = wrap(child)
+	}
+	return strings.Join(parts, sep)
+}
+
+// wrap 复合节点加括号
+func wrap(n Node) string {
+	switch n.(type) {
+	case *AndNode, *OrNode:
+		return "(" + n.String() + ")"
+	default:
+		return n.String()
+	}
+}
+
+// token 词法单元
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenField  // 字段名（不含冒号）
+	tokenWord   // 普通值
+	tokenQuoted // 引号中的值
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+// tokenize 词法分析
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, pos: i})
+			i++
+		case r == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("第 %d 个字符处的引号没有闭合", start+1)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenQuoted, value: sb.String(), pos: start})
+		case r == '-' && (i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '('):
+			// 前缀 - 等同于 NOT
+			tokens = append(tokens, token{kind: tokenNot, pos: i})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()\"", runes[i]) {
+				if runes[i] == ':' {
+					break
+				}
+				i++
+			}
+			word := string(runes[start:i])
+			if i < len(runes) && runes[i] == ':' {
+				tokens = append(tokens, token{kind: tokenField, value: strings.ToLower(word), pos: start})
+				i++
+				continue
+			}
+			switch word {
+			case "AND":
+				tokens = append(tokens, token{kind: tokenAnd, pos: start})
+			case "OR":
+				tokens = append(tokens, token{kind: tokenOr, pos: start})
+			case "NOT":
+				tokens = append(tokens, token{kind: tokenNot, pos: start})
+			default:
+				tokens = append(tokens, token{kind: tokenWord, value: word, pos: start})
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// parser 递归下降解析器
+// 语法：
+//
+//	expr    = and { "OR" and }
+//	and     = unary { ["AND"] unary }
+//	unary   = ("NOT" | "-") unary | primary
+//	primary = "(" expr ")" | field ":" (value | "(" expr ")")
+//
+// 字段后的括号内，不带字段的值都属于该字段，如 from:(a.com OR b.com)
+type parser struct {
+	tokens []token
+	pos    int
+	field  Field          // 当前括号组的默认字段
+	loc    *time.Location // before:/since: 日期所在的时区
+}
+
+// Parse 解析筛选表达式，空表达式返回 nil
+// loc 为 before:/since: 日期所在的时区，应与清理请求解析日期的时区一致
+func Parse(input string, loc *time.Location) (Node, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, loc: loc}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("第 %d 个字符处有多余的内容", tok.pos+1)
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (Node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []Node{first}
+	for p.peek().kind == tokenOr {
+		p.next()
+		child, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &OrNode{Children: children}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	children := []Node{first}
+	for {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.next()
+		case tokenNot, tokenLParen, tokenField, tokenWord, tokenQuoted:
+			// 相邻条件隐式 AND
+		default:
+			if len(children) == 1 {
+				return first, nil
+			}
+			return &AndNode{Children: children}, nil
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.peek().kind == tokenNot {
+		p.next()
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotNode{Child: child}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenLParen:
+		return p.parseGroup(p.field)
+	case tokenField:
+		field := Field(tok.value)
+		if p.peek().kind == tokenLParen {
+			p.next()
+			return p.parseGroup(field)
+		}
+		val := p.next()
+		if val.kind != tokenWord && val.kind != tokenQuoted {
+			return nil, fmt.Errorf("第 %d 个字符处的 %s: 缺少值", tok.pos+1, tok.value)
+		}
+		return p.newTerm(field, val.value)
+	case tokenWord, tokenQuoted:
+		if p.field == "" {
+			return nil, fmt.Errorf("第 %d 个字符处的 %q 缺少字段，如 from:%s", tok.pos+1, tok.value, tok.value)
+		}
+		return p.newTerm(p.field, tok.value)
+	case tokenEOF:
+		return nil, fmt.Errorf("表达式不完整")
+	default:
+		return nil, fmt.Errorf("第 %d 个字符处语法错误", tok.pos+1)
+	}
+}
+
+// parseGroup 解析括号内的表达式，field 为括号内值的默认字段
+func (p *parser) parseGroup(field Field) (Node, error) {
+	saved := p.field
+	p.field = field
+	defer func() { p.field = saved }()
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.next(); tok.kind != tokenRParen {
+		return nil, fmt.Errorf("第 %d 个字符处缺少右括号", tok.pos+1)
+	}
+	return node, nil
+}
+
+// newTerm 创建并校验字段条件
+func (p *parser) newTerm(field Field, value string) (*TermNode, error) {
+	term := &TermNode{Field: field, Value: value}
+	if value == "" {
+		return nil, fmt.Errorf("%s: 的值不能为空", field)
+	}
+
+	switch field {
+	case FieldFrom, FieldTo, FieldCc, FieldSubject:
+	case FieldLarger, FieldSmaller:
+		size, err := parseBytes(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%s 大小格式错误（如 5M、100K）", field, value)
+		}
+		term.size = size
+	case FieldOlder, FieldNewer:
+		period, err := model.ParsePeriod(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%s 时间格式错误（如 30d、8w、6m、1y）", field, value)
+		}
+		term.period = period
+	case FieldBefore, FieldSince:
+		date, err := time.ParseInLocation("2006-01-02", value, p.loc)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%s 日期格式错误（YYYY-MM-DD）", field, value)
+		}
+		term.date = date
+	case FieldIs:
+		switch strings.ToLower(value) {
+		case "read", "unread", "flagged", "answered":
+			term.flag = strings.ToLower(value)
+		default:
+			return nil, fmt.Errorf("is:%s 不支持，可用 read、unread、flagged、answered", value)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的字段 %q", field)
+	}
+	return term, nil
+}
+
+// parseBytes 解析大小，如 5M、100K、1G、2048
+func parseBytes(s string) (int64, error) {
+	s = strings.TrimSuffix(strings.ToUpper(s), "B")
+	var multiplier int64 = 1
+	switch {
+	case strings.HasSuffix(s, "G"):
+		multiplier, s = 1024*1024*1024, s[:len(s)-1]
+	case strings.HasSuffix(s, "M"):
+		multiplier, s = 1024*1024, s[:len(s)-1]
+	case strings.HasSuffix(s, "K"):
+		multiplier, s = 1024, s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size")
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,406 @@
+package restorer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+
+	"CleanMyEmail/internal/archive"
+	imapClient "CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/model"
+)
+
+const (
+	maxRetries         = 3                // 最大重试次数
+	retryInterval      = 2 * time.Second  // 重试间隔
+	messageIDBatchSize = 50               // 查重时每次搜索的 Message-ID 数量
+	progressInterval   = 10               // 每恢复多少封发送一次进度
+	maxThrottleRetries = 5                // 服务器限流时的最大重试次数（不计入 maxRetries）
+	throttleInterval   = 10 * time.Second // 没有限速器时限流后的等待时间
+)
+
+// Restorer 归档恢复器，将本地归档的邮件 APPEND 回服务器
+type Restorer struct {
+	pool       *imapClient.ConnectionPool
+	ctx        context.Context
+	cancel     context.CancelFunc
+	progressCh chan *model.RestoreProgress
+	mu         sync.Mutex
+	running    bool
+}
+
+// NewRestorer 创建恢复器（使用外部连接池）
+func NewRestorer(pool *imapClient.ConnectionPool) *Restorer {
+	return &Restorer{
+		pool:       pool,
+		progressCh: make(chan *model.RestoreProgress, 100),
+	}
+}
+
+// ProgressChan 获取进度通道
+func (r *Restorer) ProgressChan() <-chan *model.RestoreProgress {
+	return r.progressCh
+}
+
+// Cancel 取消恢复
+func (r *Restorer) Cancel() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// folderGroup 恢复到同一文件夹的归档条目
+type folderGroup struct {
+	folder  string
+	entries []archive.Entry
+}
+
+// Restore 执行恢复
+// targetFolder 为空时恢复到邮件原来所在的文件夹
+func (r *Restorer) Restore(historyID int64, archiveDir, targetFolder string) (*model.RestoreResult, error) {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("恢复任务正在进行中")
+	}
+	r.running = true
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.running = false
+		r.mu.Unlock()
+		close(r.progressCh)
+	}()
+
+	entries, err := archive.ReadIndex(archiveDir)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	result := &model.RestoreResult{
+		HistoryID:  historyID,
+		TotalCount: len(entries),
+		Status:     "completed",
+	}
+
+	groups := groupByFolder(entries, targetFolder)
+	for i, group := range groups {
+		if r.ctx.Err() != nil {
+			result.Status = "cancelled"
+			break
+		}
+		if err := r.restoreFolder(historyID, archiveDir, group, i, len(groups), result, startTime); err != nil {
+			if r.ctx.Err() != nil {
+				result.Status = "cancelled"
+				break
+			}
+			log.Printf("[WARN] [%s] 恢复失败: %v", group.folder, err)
+			result.Status = "failed"
+			result.Error = fmt.Sprintf("文件夹 %s 恢复失败: %v", group.folder, err)
+		}
+	}
+
+	result.Duration = time.Since(startTime).Seconds()
+	r.sendProgress(&model.RestoreProgress{
+		HistoryID:      historyID,
+		TotalCount:     result.TotalCount,
+		RestoredCount:  result.RestoredCount,
+		SkippedCount:   result.SkippedCount,
+		FailedCount:    result.FailedCount,
+		Status:         result.Status,
+		ElapsedSeconds: result.Duration,
+		Message: fmt.Sprintf("恢复完成，共恢复 %d 封，跳过 %d 封已存在的邮件",
+			result.RestoredCount, result.SkippedCount),
+	})
+
+	return result, nil
+}
+
+// groupByFolder 按目标文件夹分组，保持归档中的顺序
+func groupByFolder(entries []archive.Entry, targetFolder string) []*folderGroup {
+	var groups []*folderGroup
+	index := make(map[string]*folderGroup)
+	for _, entry := range entries {
+		folder := entry.Folder
+		if targetFolder != "" {
+			folder = targetFolder
+		}
+		group, ok := index[folder]
+		if !ok {
+			group = &folderGroup{folder: folder}
+			index[folder] = group
+			groups = append(groups, group)
+		}
+		group.entries = append(group.entries, entry)
+	}
+	return groups
+}
+
+// restoreFolder 恢复单个文件夹
+func (r *Restorer) restoreFolder(historyID int64, archiveDir string, group *folderGroup, folderIdx, totalFolders int,
+	result *model.RestoreResult, startTime time.Time) error {
+	conn, err := r.getConnection()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if conn != nil {
+			conn.Release()
+		}
+	}()
+
+	if _, err := conn.Client().Select(group.folder, nil).Wait(); err != nil {
+		return fmt.Errorf("选择文件夹失败: %w", err)
+	}
+
+	existing, err := r.findExistingMessageIDs(conn.Client(), group.entries)
+	if err != nil {
+		return fmt.Errorf("检查已存在邮件失败: %w", err)
+	}
+
+	sendProgress := func(message string) {
+		r.sendProgress(&model.RestoreProgress{
+			HistoryID:      historyID,
+			CurrentFolder:  group.folder,
+			FolderIndex:    folderIdx + 1,
+			TotalFolders:   totalFolders,
+			TotalCount:     result.TotalCount,
+			RestoredCount:  result.RestoredCount,
+			SkippedCount:   result.SkippedCount,
+			FailedCount:    result.FailedCount,
+			Status:         "running",
+			Message:        message,
+			ElapsedSeconds: time.Since(startTime).Seconds(),
+		})
+	}
+
+	for i, entry := range group.entries {
+		if r.ctx.Err() != nil {
+			return fmt.Errorf("操作已取消")
+		}
+
+		if entry.MessageID != "" && existing[entry.MessageID] {
+			result.SkippedCount++
+		} else {
+			body, err := archive.ReadMessage(archiveDir, entry)
+			if err != nil {
+				log.Printf("[WARN] [%s] 读取归档邮件 UID %d 失败: %v", group.folder, entry.UID, err)
+				result.FailedCount++
+				continue
+			}
+			if conn, err = r.appendWithRetry(conn, group.folder, entry, body); err != nil {
+				log.Printf("[WARN] [%s] 恢复邮件 UID %d 失败: %v", group.folder, entry.UID, err)
+				result.FailedCount++
+				if conn == nil {
+					return err
+				}
+				continue
+			}
+			result.RestoredCount++
+			if entry.MessageID != "" {
+				existing[entry.MessageID] = true
+			}
+		}
+
+		if (i+1)%progressInterval == 0 || i == len(group.entries)-1 {
+			sendProgress(fmt.Sprintf("文件夹 %s: %d/%d 已处理", group.folder, i+1, len(group.entries)))
+		}
+	}
+	return nil
+}
+
+// appendWithRetry 带重连的 APPEND，保留原始标记和 INTERNALDATE
+// 返回当前可用的连接；连接无法恢复时返回 nil
+func (r *Restorer) appendWithRetry(conn *imapClient.PooledConn, folder string, entry archive.Entry, body []byte) (*imapClient.PooledConn, error) {
+	options := &imap.AppendOptions{
+		Flags: appendFlags(entry.Flags),
+		Time:  entry.InternalDate,
+	}
+
+	var lastErr error
+	throttled := 0
+	for retry := 0; retry < maxRetries; retry++ {
+		if err := r.throttle(); err != nil {
+			return conn, err
+		}
+
+		if lastErr = imapClient.AppendMessage(conn.Client(), folder, body, options); lastErr == nil {
+			return conn, nil
+		}
+
+		// 服务器限流：连接本身没有问题，退避后在同一连接上重试
+		if imapClient.IsThrottled(lastErr) && throttled < maxThrottleRetries {
+			throttled++
+			retry--
+			delay := r.backoff()
+			log.Printf("[DEBUG] [%s] 服务器限流，%v 后重试 (%d/%d): %v", folder, delay, throttled, maxThrottleRetries, lastErr)
+			continue
+		}
+
+		if retry < maxRetries-1 {
+			log.Printf("[DEBUG] APPEND 失败，%v 后重试 (%d/%d): %v", retryInterval, retry+1, maxRetries, lastErr)
+			time.Sleep(retryInterval)
+
+			conn.MarkBad()
+			var err error
+			if conn, err = r.getConnection(); err != nil {
+				return nil, fmt.Errorf("重新获取连接失败: %w", err)
+			}
+		}
+	}
+	return conn, fmt.Errorf("恢复失败，已重试 %d 次: %w", maxRetries, lastErr)
+}
+
+// appendFlags 转换归档中的标记（\Recent 由服务器维护，不能通过 APPEND 设置）
+// 去掉 \Deleted：继续中断的清理时备份的邮件可能已被标记删除，带着它恢复会在下次清除时再次被删除
+func appendFlags(flags []string) []imap.Flag {
+	result := make([]imap.Flag, 0, len(flags))
+	for _, flag := range flags {
+		if strings.EqualFold(flag, `\Recent`) || strings.EqualFold(flag, string(imap.FlagDeleted)) {
+			continue
+		}
+		result = append(result, imap.Flag(flag))
+	}
+	return result
+}
+
+// findExistingMessageIDs 查找目标文件夹中已存在的 Message-ID
+// 分批用 OR 组合 HEADER Message-ID 条件搜索，再获取信封确认具体的 Message-ID
+func (r *Restorer) findExistingMessageIDs(client *imapclient.Client, entries []archive.Entry) (map[string]bool, error) {
+	existing := make(map[string]bool)
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.MessageID != "" && !seen[entry.MessageID] {
+			seen[entry.MessageID] = true
+			ids = append(ids, entry.MessageID)
+		}
+	}
+
+	for i := 0; i < len(ids); i += messageIDBatchSize {
+		if r.ctx.Err() != nil {
+			return nil, fmt.Errorf("操作已取消")
+		}
+
+		batch := ids[i:min(i+messageIDBatchSize, len(ids))]
+		if err := r.throttle(); err != nil {
+			return nil, err
+		}
+		searchData, err := client.UIDSearch(buildMessageIDCriteria(batch), nil).Wait()
+		if err != nil {
+			return nil, err
+		}
+		uids := searchData.AllUIDs()
+		if len(uids) == 0 {
+			continue
+		}
+
+		uidSet := imap.UIDSet{}
+		for _, uid := range uids {
+			uidSet.AddNum(uid)
+		}
+		if err := r.throttle(); err != nil {
+			return nil, err
+		}
+		fetchCmd := client.Fetch(uidSet, &imap.FetchOptions{Envelope: true})
+		for msg := fetchCmd.Next(); msg != nil; msg = fetchCmd.Next() {
+			for item := msg.Next(); item != nil; item = msg.Next() {
+				if data, ok := item.(imapclient.FetchItemDataEnvelope); ok && data.Envelope != nil {
+					if id := data.Envelope.MessageID; seen[id] {
+						existing[id] = true
+					}
+				}
+			}
+		}
+		if err := fetchCmd.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return existing, nil
+}
+
+// buildMessageIDCriteria 构建 Message-ID 的 OR 搜索条件
+func buildMessageIDCriteria(ids []string) *imap.SearchCriteria {
+	criteria := imap.SearchCriteria{
+		Header: []imap.SearchCriteriaHeaderField{{Key: "Message-ID", Value: ids[len(ids)-1]}},
+	}
+	for i := len(ids) - 2; i >= 0; i-- {
+		current := imap.SearchCriteria{
+			Header: []imap.SearchCriteriaHeaderField{{Key: "Message-ID", Value: ids[i]}},
+		}
+		criteria = imap.SearchCriteria{Or: [][2]imap.SearchCriteria{{current, criteria}}}
+	}
+	return &criteria
+}
+
+// throttle 发送 IMAP 命令前等待同一主机的限速器，取消时返回错误
+func (r *Restorer) throttle() error {
+	if r.ctx.Err() != nil {
+		return fmt.Errorf("操作已取消")
+	}
+	if limiter := r.pool.Limiter(); limiter != nil {
+		if err := limiter.Wait(r.ctx); err != nil {
+			return fmt.Errorf("操作已取消")
+		}
+	}
+	return nil
+}
+
+// backoff 服务器限流后退避：有限速器时暂停同一主机的所有命令，否则直接等待
+func (r *Restorer) backoff() time.Duration {
+	if limiter := r.pool.Limiter(); limiter != nil {
+		return limiter.Backoff()
+	}
+	time.Sleep(throttleInterval)
+	return throttleInterval
+}
+
+// getConnection 从连接池获取连接（带重试）
+func (r *Restorer) getConnection() (*imapClient.PooledConn, error) {
+	var lastErr error
+	for retry := 0; retry < maxRetries; retry++ {
+		if r.ctx.Err() != nil {
+			return nil, fmt.Errorf("操作已取消")
+		}
+
+		if conn, err := r.pool.Get(r.ctx); err == nil {
+			return conn, nil
+		} else {
+			lastErr = err
+		}
+
+		if retry < maxRetries-1 {
+			log.Printf("[DEBUG] 获取连接失败，%v 后重试 (%d/%d): %v", retryInterval, retry+1, maxRetries, lastErr)
+			time.Sleep(retryInterval)
+		}
+	}
+	return nil, fmt.Errorf("获取连接失败，已重试 %d 次: %w", maxRetries, lastErr)
+}
+
+// sendProgress 发送进度
+// 结束时的进度（completed、cancelled、failed）阻塞发送，确保最终状态不会因通道已满而丢失；
+// 消费方需读取到通道关闭为止
+func (r *Restorer) sendProgress(progress *model.RestoreProgress) {
+	if progress.Status != "running" {
+		r.progressCh <- progress
+		return
+	}
+	select {
+	case r.progressCh <- progress:
+	default:
+		// 通道满了就丢弃
+	}
+}
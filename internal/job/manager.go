@@ -0,0 +1,299 @@
+// Package job 清理任务管理：按任务 ID 管理并发执行的清理，不同账号的清理可以同时进行
+package job
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"CleanMyEmail/internal/email/cleaner"
+	"CleanMyEmail/internal/model"
+)
+
+const maxFinishedJobs = 50 // 保留的已结束任务数量，更早的任务从列表中移除
+
+// EventHandler 任务事件回调，event 为 job:start / job:progress / job:pause / job:resume / job:complete
+// job:progress 的数据为 *model.CleanProgress，其余为 *model.CleanJob
+type EventHandler func(event string, data any)
+
+// Task 待执行的清理任务
+type Task struct {
+	Job     *model.CleanJob // 任务信息，ID、状态和时间由管理器填写
+	Cleaner *cleaner.Cleaner
+	Request *model.CleanRequest
+	// OnProgress 收到进度时调用（可选），进度已填写 JobID
+	OnProgress func(progress *model.CleanProgress)
+	// OnDone 清理结束后、任务标记为结束之前调用（可选），用于记录历史等收尾工作；可修改 result
+	OnDone func(result *model.CleanResult, err error)
+}
+
+// entry 管理器中的任务
+type entry struct {
+	job     *model.CleanJob
+	cleaner *cleaner.Cleaner
+}
+
+// Manager 清理任务管理器，同一账号同时只允许一个会删除邮件的任务（预览不受限制）
+type Manager struct {
+	mu      sync.Mutex
+	nextID  int64
+	jobs    map[int64]*entry
+	onEvent EventHandler
+}
+
+// NewManager 创建任务管理器
+func NewManager() *Manager {
+	return &Manager{jobs: make(map[int64]*entry)}
+}
+
+// SetEventHandler 设置任务事件回调
+func (m *Manager) SetEventHandler(handler EventHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onEvent = handler
+}
+
+// CheckAvailable 检查账号能否开始新任务：destructive 为 true 时，账号不能有正在执行的删除类任务
+// 用于在创建历史记录、领取清理计划等操作之前提前报错，Start/Run 时会再次检查
+func (m *Manager) CheckAvailable(accountID int64, destructive bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.checkAvailableLocked(accountID, destructive)
+}
+
+// checkAvailableLocked 调用方需持有 mu
+func (m *Manager) checkAvailableLocked(accountID int64, destructive bool) error {
+	if !destructive {
+		return nil
+	}
+	for _, e := range m.jobs {
+		if e.job.Active() && e.job.AccountID == accountID && e.job.Destructive() {
+			return fmt.Errorf("账号 %s 已有正在执行的清理任务（任务 %d），请等待其结束或先取消", e.job.AccountEmail, e.job.ID)
+		}
+	}
+	return nil
+}
+
+// Start 登记任务并在后台执行，账号已有删除类任务时返回错误
+func (m *Manager) Start(task *Task) (*model.CleanJob, error) {
+	e, err := m.register(task)
+	if err != nil {
+		return nil, err
+	}
+	go m.execute(e, task)
+	return m.snapshot(e), nil
+}
+
+// Run 登记任务并同步执行，返回清理结果（定时清理使用）
+func (m *Manager) Run(task *Task) (*model.CleanResult, error) {
+	e, err := m.register(task)
+	if err != nil {
+		return nil, err
+	}
+	return m.execute(e, task)
+}
+
+// register 检查冲突并登记任务
+func (m *Manager) register(task *Task) (*entry, error) {
+	m.mu.Lock()
+	if err := m.checkAvailableLocked(task.Job.AccountID, task.Job.Destructive()); err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	m.nextID++
+	task.Job.ID = m.nextID
+	task.Job.Status = "running"
+	task.Job.StartedAt = time.Now()
+	e := &entry{job: task.Job, cleaner: task.Cleaner}
+	m.jobs[e.job.ID] = e
+	m.mu.Unlock()
+
+	m.emit("job:start", m.snapshot(e))
+	return e, nil
+}
+
+// execute 执行清理，转发进度并在结束后更新任务状态
+func (m *Manager) execute(e *entry, task *Task) (*model.CleanResult, error) {
+	progressDone := make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		for progress := range task.Cleaner.ProgressChan() {
+			progress.JobID = e.job.ID
+			m.mu.Lock()
+			e.job.Progress = progress
+			m.mu.Unlock()
+			if task.OnProgress != nil {
+				task.OnProgress(progress)
+			}
+			m.emit("job:progress", progress)
+		}
+	}()
+
+	result, err := task.Cleaner.Clean(task.Request)
+	<-progressDone
+	if task.OnDone != nil {
+		task.OnDone(result, err)
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	e.job.FinishedAt = &now
+	switch {
+	case err != nil:
+		e.job.Status = "failed"
+		e.job.Error = err.Error()
+	default:
+		e.job.Status = result.Status
+		e.job.Result = result
+	}
+	m.pruneLocked()
+	m.mu.Unlock()
+
+	m.emit("job:complete", m.snapshot(e))
+	return result, err
+}
+
+// Pause 暂停任务：清理器在当前批次结束后归还连接并等待继续
+func (m *Manager) Pause(id int64) (*model.CleanJob, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("任务不存在")
+	}
+	if e.job.Status != "running" {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("任务未在执行中，无法暂停")
+	}
+	if err := e.cleaner.Pause(); err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	e.job.Status = "paused"
+	m.mu.Unlock()
+
+	job := m.snapshot(e)
+	m.emit("job:pause", job)
+	return job, nil
+}
+
+// Resume 继续暂停的任务
+func (m *Manager) Resume(id int64) (*model.CleanJob, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("任务不存在")
+	}
+	if e.job.Status != "paused" {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("任务未暂停")
+	}
+	if err := e.cleaner.Resume(); err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	e.job.Status = "running"
+	m.mu.Unlock()
+
+	job := m.snapshot(e)
+	m.emit("job:resume", job)
+	return job, nil
+}
+
+// Cancel 取消任务（已暂停的任务也可以取消）
+func (m *Manager) Cancel(id int64) error {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	running := ok && e.job.Active()
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("任务不存在")
+	}
+	if !running {
+		return fmt.Errorf("任务已结束")
+	}
+	e.cleaner.Cancel()
+	return nil
+}
+
+// CancelWhere 取消所有满足条件的正在执行的任务，返回取消的任务数
+func (m *Manager) CancelWhere(match func(job *model.CleanJob) bool) int {
+	m.mu.Lock()
+	var cleaners []*cleaner.Cleaner
+	for _, e := range m.jobs {
+		if e.job.Active() && match(e.job) {
+			cleaners = append(cleaners, e.cleaner)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, c := range cleaners {
+		c.Cancel()
+	}
+	return len(cleaners)
+}
+
+// Get 获取任务
+func (m *Manager) Get(id int64) (*model.CleanJob, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("任务不存在")
+	}
+	return m.snapshot(e), nil
+}
+
+// List 获取正在执行和最近结束的任务，按开始时间倒序
+func (m *Manager) List() []*model.CleanJob {
+	m.mu.Lock()
+	entries := make([]*entry, 0, len(m.jobs))
+	for _, e := range m.jobs {
+		entries = append(entries, e)
+	}
+	m.mu.Unlock()
+
+	jobs := make([]*model.CleanJob, 0, len(entries))
+	for _, e := range entries {
+		jobs = append(jobs, m.snapshot(e))
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID > jobs[j].ID })
+	return jobs
+}
+
+// pruneLocked 移除超出保留数量的已结束任务，调用方需持有 mu
+func (m *Manager) pruneLocked() {
+	var finished []int64
+	for id, e := range m.jobs {
+		if !e.job.Active() {
+			finished = append(finished, id)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i] < finished[j] })
+	for _, id := range finished[:len(finished)-maxFinishedJobs] {
+		delete(m.jobs, id)
+	}
+}
+
+// snapshot 复制任务信息，避免调用方读取时与执行中的更新竞争
+func (m *Manager) snapshot(e *entry) *model.CleanJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := *e.job
+	return &job
+}
+
+// emit 发送任务事件
+func (m *Manager) emit(event string, data any) {
+	m.mu.Lock()
+	handler := m.onEvent
+	m.mu.Unlock()
+	if handler != nil {
+		handler(event, data)
+	}
+}
@@ -0,0 +1,167 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// AnalysisDimension 分析报告的统计维度
+type AnalysisDimension string
+
+const (
+	AnalysisBySender AnalysisDimension = "sender" // 按发件人地址
+	AnalysisByDomain AnalysisDimension = "domain" // 按发件人域名
+	AnalysisByMonth  AnalysisDimension = "month"  // 按月份（YYYY-MM）
+	AnalysisByFolder AnalysisDimension = "folder" // 按文件夹
+)
+
+// AnalysisUnknownKey 无法解析发件人或日期时使用的统计键，不能转换为清理条件
+const AnalysisUnknownKey = "(未知)"
+
+// AnalysisRequest 邮箱分析请求
+type AnalysisRequest struct {
+	AccountID      int64    `json:"accountId"`
+	Folders        []string `json:"folders"`
+	StartDate      string   `json:"startDate"` // YYYY-MM-DD，可选
+	EndDate        string   `json:"endDate"`   // YYYY-MM-DD，可选
+	MaxConcurrency int      `json:"maxConcurrency"`
+}
+
+// AnalysisRow 分析报告中的一行统计
+type AnalysisRow struct {
+	Key   string `json:"key"` // 发件人地址、域名、月份或文件夹
+	Count int    `json:"count"`
+	Size  int64  `json:"size"` // 字节数（RFC822.SIZE 之和）
+}
+
+// AnalysisReport 邮箱分析报告：按发件人、域名、月份和文件夹统计邮件数量和占用空间
+type AnalysisReport struct {
+	ID           int64         `json:"id"`
+	AccountID    int64         `json:"accountId"`
+	AccountEmail string        `json:"accountEmail"`
+	Folders      []string      `json:"folders"` // 分析的文件夹
+	StartDate    string        `json:"startDate"`
+	EndDate      string        `json:"endDate"`
+	TotalCount   int           `json:"totalCount"`
+	TotalSize    int64         `json:"totalSize"`
+	BySender     []AnalysisRow `json:"bySender"` // 按数量降序，只保留前若干行
+	ByDomain     []AnalysisRow `json:"byDomain"`
+	ByMonth      []AnalysisRow `json:"byMonth"` // 按月份升序（按 INTERNALDATE 和设置中的时区，与清理时的日期判断一致）
+	ByFolder     []AnalysisRow `json:"byFolder"`
+	Status       string        `json:"status"` // completed, cancelled, failed
+	Error        string        `json:"error,omitempty"`
+	Duration     float64       `json:"duration"`
+	CreatedAt    time.Time     `json:"createdAt"`
+}
+
+// AnalysisListItem 分析报告列表项
+type AnalysisListItem struct {
+	ID           int64     `json:"id"`
+	AccountID    int64     `json:"accountId"`
+	AccountEmail string    `json:"accountEmail"`
+	FolderCount  int       `json:"folderCount"`
+	TotalCount   int       `json:"totalCount"`
+	TotalSize    int64     `json:"totalSize"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// AnalysisProgress 分析进度
+type AnalysisProgress struct {
+	AccountID      int64   `json:"accountId"`
+	CurrentFolder  string  `json:"currentFolder"`
+	FolderIndex    int     `json:"folderIndex"`
+	TotalFolders   int     `json:"totalFolders"`
+	ScannedCount   int     `json:"scannedCount"`
+	Status         string  `json:"status"` // running, completed, failed, cancelled
+	Message        string  `json:"message"`
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+}
+
+// CleanRequestFor 将报告中的一行转换为清理请求（预览模式），文件夹和日期范围沿用报告的设置
+func (r *AnalysisReport) CleanRequestFor(dimension AnalysisDimension, key string, now time.Time) (*CleanRequest, error) {
+	req := &CleanRequest{
+		AccountID:   r.AccountID,
+		Folders:     append([]string(nil), r.Folders...),
+		StartDate:   r.StartDate,
+		EndDate:     r.EndDate,
+		PreviewOnly: true,
+	}
+	if req.EndDate == "" {
+		req.EndDate = now.Format("2006-01-02")
+	}
+
+	switch dimension {
+	case AnalysisBySender:
+		req.FilterSender = key
+	case AnalysisByDomain:
+		req.FilterSender = "@" + key
+	case AnalysisByMonth:
+		month, err := time.Parse("2006-01", key)
+		if err != nil {
+			return nil, fmt.Errorf("月份格式错误: %s", key)
+		}
+		req.StartDate = month.Format("2006-01-02")
+		req.EndDate = month.AddDate(0, 1, -1).Format("2006-01-02")
+	case AnalysisByFolder:
+		req.Folders = []string{key}
+	default:
+		return nil, fmt.Errorf("不支持的统计维度: %s", dimension)
+	}
+	return req, nil
+}
+
+// LargestMessagesRequest 查询账号中占用空间最大的邮件
+type LargestMessagesRequest struct {
+	AccountID      int64    `json:"accountId"`
+	Folders        []string `json:"folders"` // 为空时查询所有可选择的文件夹
+	Limit          int      `json:"limit"`   // 返回的邮件数，默认 100，最多 1000
+	MaxConcurrency int      `json:"maxConcurrency"`
+	// 执行生成的清理计划时使用
+	DeleteMode         DeleteMode `json:"deleteMode"`
+	TargetFolder       string     `json:"targetFolder"`
+	BackupBeforeDelete bool       `json:"backupBeforeDelete"`
+}
+
+// GetLimit 获取返回的邮件数
+func (r *LargestMessagesRequest) GetLimit() int {
+	switch {
+	case r.Limit <= 0:
+		return 100
+	case r.Limit > 1000:
+		return 1000
+	default:
+		return r.Limit
+	}
+}
+
+// PlanRequest 生成清理计划使用的清理请求，执行计划时只删除结果中的 UID，日期仅用于通过参数校验
+// 请求本身不含任何筛选条件，标记为只能按计划执行，避免在没有计划时删除文件夹中的所有邮件
+func (r *LargestMessagesRequest) PlanRequest(folders []*CleanPlanFolder, now time.Time) *CleanRequest {
+	names := make([]string, len(folders))
+	for i, f := range folders {
+		names[i] = f.Folder
+	}
+	return &CleanRequest{
+		AccountID:          r.AccountID,
+		Folders:            names,
+		EndDate:            now.Format("2006-01-02"),
+		MaxConcurrency:     r.MaxConcurrency,
+		DeleteMode:         r.DeleteMode,
+		TargetFolder:       r.TargetFolder,
+		BackupBeforeDelete: r.BackupBeforeDelete,
+		PlanOnly:           true,
+	}
+}
+
+// LargestMessagesResult 占用空间最大的邮件，结果会生成清理计划，可直接执行删除
+type LargestMessagesResult struct {
+	AccountID    int64             `json:"accountId"`
+	Messages     []*PreviewMessage `json:"messages"`     // 按大小降序
+	TotalSize    int64             `json:"totalSize"`    // 列出邮件的总大小
+	ScannedCount int               `json:"scannedCount"` // 扫描的邮件数
+	PlanID       int64             `json:"planId,omitempty"`
+	Status       string            `json:"status"` // completed, cancelled, failed
+	Error        string            `json:"error,omitempty"`
+	Duration     float64           `json:"duration"`
+}
@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// 删除审计记录状态：删除前先写入 pending，删除结束后更新
+const (
+	DeletedMessagePending = "pending" // 已记录，删除尚未确认（删除过程中应用退出时保持该状态）
+	DeletedMessageDeleted = "deleted" // 已删除
+	DeletedMessageFailed  = "failed"  // 删除失败，邮件仍在服务器上（重试时会另行记录）
+)
+
+// DeletedMessage 删除审计记录：一封被删除、移动或移除附件的邮件
+// 清理历史被删除后审计记录仍然保留
+type DeletedMessage struct {
+	ID           int64     `json:"id"`
+	HistoryID    int64     `json:"historyId"` // 对应的清理历史记录
+	AccountID    int64     `json:"accountId"`
+	AccountEmail string    `json:"accountEmail"`
+	Folder       string    `json:"folder"`
+	UID          uint32    `json:"uid"`
+	MessageID    string    `json:"messageId"` // Message-ID 头，不含尖括号
+	From         string    `json:"from"`
+	To           string    `json:"to"` // 多个收件人以逗号分隔
+	Subject      string    `json:"subject"`
+	Date         time.Time `json:"date"` // 邮件头日期
+	Size         int64     `json:"size"`
+	Action       string    `json:"action"`    // permanent, trash, folder, strip_attachments
+	Status       string    `json:"status"`    // pending, deleted, failed
+	DeletedAt    time.Time `json:"deletedAt"` // 删除时间，尚未确认时为记录时间
+}
+
+// DeletedMessageQuery 删除审计记录查询，各条件同时满足
+type DeletedMessageQuery struct {
+	AccountID int64  `json:"accountId"` // 为 0 时不限账号
+	HistoryID int64  `json:"historyId"` // 为 0 时不限清理任务
+	Sender    string `json:"sender"`    // 发件人包含该文本
+	Subject   string `json:"subject"`   // 主题包含该文本
+	MessageID string `json:"messageId"` // Message-ID 完全一致（尖括号可有可无）
+	Page      int    `json:"page"`      // 从 1 开始
+	PageSize  int    `json:"pageSize"`  // 默认 50
+}
+
+// DeletedMessagePage 删除审计记录的一页，按删除时间倒序
+type DeletedMessagePage struct {
+	Total    int               `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"pageSize"`
+	Messages []*DeletedMessage `json:"messages"`
+}
+
+// AuditExportFormat 删除审计记录的导出格式
+type AuditExportFormat string
+
+const (
+	AuditExportCSV  AuditExportFormat = "csv"
+	AuditExportJSON AuditExportFormat = "json"
+)
+
+// AuditExportResult 删除审计记录的导出结果
+type AuditExportResult struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
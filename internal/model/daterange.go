@@ -0,0 +1,97 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HasRelativeDate 是否设置了相对日期（OlderThan 或 Between）
+func (r *CleanRequest) HasRelativeDate() bool {
+	return r.OlderThan != "" || r.Between != ""
+}
+
+// DateExpression 相对日期表达式的文字描述，如 "olderThan: 90d"、"between: 2y..1y"，未设置时为空
+func (r *CleanRequest) DateExpression() string {
+	switch {
+	case r.Between != "":
+		return "between: " + r.Between
+	case r.OlderThan != "":
+		return "olderThan: " + r.OlderThan
+	default:
+		return ""
+	}
+}
+
+// ResolveDates 按执行时间解析相对日期，覆盖 StartDate/EndDate（均为包含当天的日期）
+// now 应为用户时区的当前时间；未设置相对日期时不做修改
+//   - OlderThan "90d"：EndDate 为 90 天前的前一天，即只匹配 90 天之前的邮件
+//   - Between "2y..1y"：StartDate 为 2 年前，EndDate 为 1 年前的前一天
+func (r *CleanRequest) ResolveDates(now time.Time) error {
+	if r.OlderThan != "" && r.Between != "" {
+		return fmt.Errorf("olderThan 和 between 不能同时设置")
+	}
+	switch {
+	case r.OlderThan != "":
+		period, err := ParsePeriod(r.OlderThan)
+		if err != nil {
+			return fmt.Errorf("olderThan: %s 格式错误（如 90d、8w、6m、1y）", r.OlderThan)
+		}
+		r.StartDate = ""
+		r.EndDate = periodCutoff(now, period).AddDate(0, 0, -1).Format("2006-01-02")
+	case r.Between != "":
+		from, to, ok := strings.Cut(r.Between, "..")
+		if !ok {
+			return fmt.Errorf("between: %s 格式错误（如 2y..1y）", r.Between)
+		}
+		fromPeriod, err := ParsePeriod(strings.TrimSpace(from))
+		if err != nil {
+			return fmt.Errorf("between: %s 格式错误（如 2y..1y）", r.Between)
+		}
+		toPeriod, err := ParsePeriod(strings.TrimSpace(to))
+		if err != nil {
+			return fmt.Errorf("between: %s 格式错误（如 2y..1y）", r.Between)
+		}
+		start, end := periodCutoff(now, fromPeriod), periodCutoff(now, toPeriod)
+		if !start.Before(end) {
+			return fmt.Errorf("between: %s 的起点应早于终点（较大的时间在前，如 2y..1y）", r.Between)
+		}
+		r.StartDate = start.Format("2006-01-02")
+		r.EndDate = end.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	return nil
+}
+
+// periodCutoff now 往前推 period 后的当天零点（now 所在时区）
+func periodCutoff(now time.Time, period [3]int) time.Time {
+	t := now.AddDate(-period[0], -period[1], -period[2])
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// ParsePeriod 解析相对时间，如 90d、8w、6m、2y，返回年、月、日偏移（筛选表达式的 older:/newer: 共用）
+// 数量必须大于 0：0d 表示截至今天的所有邮件，不能作为相对日期
+func ParsePeriod(s string) ([3]int, error) {
+	var period [3]int
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return period, fmt.Errorf("invalid period")
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return period, fmt.Errorf("invalid period")
+	}
+	switch strings.ToLower(s[len(s)-1:]) {
+	case "d":
+		period[2] = n
+	case "w":
+		period[2] = n * 7
+	case "m":
+		period[1] = n
+	case "y":
+		period[0] = n
+	default:
+		return period, fmt.Errorf("invalid period")
+	}
+	return period, nil
+}
@@ -62,6 +62,21 @@ func (e EmailVendorType) GetRefreshTokenLifetime() time.Duration {
 	}
 }
 
+// DefaultRateLimit 获取默认的 IMAP 命令限速
+// Gmail、QQ 和网易邮箱在大量删除时容易触发限流或临时封禁，默认限速较低
+func (e EmailVendorType) DefaultRateLimit() RateLimit {
+	switch e {
+	case EmailVendorGmail:
+		return RateLimit{CommandsPerSecond: 4, Burst: 8}
+	case EmailVendorQQ, EmailVendorNE163Personal, EmailVendorNE163Enterprise, EmailVendorNE126:
+		return RateLimit{CommandsPerSecond: 2, Burst: 4}
+	case EmailVendorOutlook, EmailVendorAliyun:
+		return RateLimit{CommandsPerSecond: 5, Burst: 10}
+	default:
+		return RateLimit{CommandsPerSecond: 10, Burst: 20}
+	}
+}
+
 // EmailAuthType 认证类型
 type EmailAuthType string
 
@@ -99,3 +114,42 @@ const (
 	OAuth2AuthStatusExpired OAuth2AuthStatus = "expired"
 	OAuth2AuthStatusError   OAuth2AuthStatus = "error"
 )
+
+// DeleteMode 删除方式
+type DeleteMode string
+
+const (
+	DeleteModePermanent DeleteMode = "permanent" // 永久删除（STORE \Deleted + EXPUNGE）
+	DeleteModeTrash     DeleteMode = "trash"     // 移动到废纸篓（通过 \Trash 特殊用途属性识别）
+	DeleteModeFolder    DeleteMode = "folder"    // 移动到指定文件夹
+)
+
+// IsMove 是否为移动类删除（邮件可恢复）
+func (m DeleteMode) IsMove() bool {
+	return m == DeleteModeTrash || m == DeleteModeFolder
+}
+
+// ExpungeMode 文件夹实际使用的清除方式
+type ExpungeMode string
+
+const (
+	ExpungeModeMove ExpungeMode = "move" // MOVE 扩展，无需 EXPUNGE
+	ExpungeModeUID  ExpungeMode = "uid"  // UID EXPUNGE（UIDPLUS），只清除本批次邮件
+	ExpungeModeFull ExpungeMode = "full" // 普通 EXPUNGE，会清除文件夹中所有已标记 \Deleted 的邮件
+)
+
+// CleanAction 清理动作
+type CleanAction string
+
+const (
+	CleanActionDelete           CleanAction = "delete"            // 删除匹配的邮件（按 DeleteMode）
+	CleanActionStripAttachments CleanAction = "strip_attachments" // 附件保存到本地，邮件去掉附件后重新上传，再删除原邮件
+)
+
+// DateBasis 日期条件依据的邮件日期
+type DateBasis string
+
+const (
+	DateBasisInternal DateBasis = "internal" // 服务器收到邮件的时间（INTERNALDATE，SINCE/BEFORE）
+	DateBasisSent     DateBasis = "sent"     // 邮件的 Date: 头（发送时间，SENTSINCE/SENTBEFORE），适合迁移过的邮箱
+)
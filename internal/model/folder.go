@@ -1,5 +1,7 @@
 package model
 
+import "strings"
+
 // MailFolder 邮箱文件夹
 type MailFolder struct {
 	Name        string        `json:"name"`
@@ -7,6 +9,7 @@ type MailFolder struct {
 	Delimiter   string        `json:"delimiter"`
 	MessageCount uint32       `json:"messageCount"`
 	UnseenCount  uint32       `json:"unseenCount"`
+	Size         int64        `json:"size"` // 占用空间（字节），来自 STATUS=SIZE 或逐封 RFC822.SIZE 累加
 	Attributes  []string      `json:"attributes"`
 	Children    []*MailFolder `json:"children,omitempty"`
 	IsSelectable bool         `json:"isSelectable"`
@@ -18,6 +21,7 @@ type FolderTreeNode struct {
 	Label        string            `json:"label"`
 	FullPath     string            `json:"fullPath"`
 	MessageCount uint32            `json:"messageCount"`
+	Size         int64             `json:"size"`
 	IsLeaf       bool              `json:"isLeaf"`
 	Disabled     bool              `json:"disabled"`
 	Children     []*FolderTreeNode `json:"children,omitempty"`
@@ -28,17 +32,146 @@ type CleanRequest struct {
 	AccountID      int64    `json:"accountId"`
 	Folders        []string `json:"folders"`
 	StartDate      string   `json:"startDate"` // YYYY-MM-DD
-	EndDate        string   `json:"endDate"`   // YYYY-MM-DD
+	EndDate        string   `json:"endDate"`   // YYYY-MM-DD，设置了 FilterQuery 时可为空
+	// 相对日期，执行时按用户时区解析并覆盖 StartDate/EndDate，见 ResolveDates
+	OlderThan string `json:"olderThan,omitempty"` // 如 "90d"：只清理 90 天之前的邮件
+	Between   string `json:"between,omitempty"`   // 如 "2y..1y"：清理 2 年前到 1 年前之间的邮件
+	// 日期依据：internal（默认，服务器收到的时间）或 sent（Date: 头），同时作用于筛选表达式中的日期条件
+	DateBasis DateBasis `json:"dateBasis,omitempty"`
 	PreviewOnly    bool     `json:"previewOnly"`
 	BatchSize      int      `json:"batchSize"`      // 每批处理的邮件数量，默认500
 	MaxConcurrency int      `json:"maxConcurrency"` // 最大并发文件夹数，默认5
+	// 自适应批大小：从较小的批开始，按服务器响应耗时和错误自动调整，BatchSize 作为上限（未设置时为 2000）
+	AdaptiveBatch bool `json:"adaptiveBatch,omitempty"`
 	// 筛选条件
 	FilterSender  string `json:"filterSender"`  // 发件人筛选（支持多个，逗号分隔）
 	FilterSubject string `json:"filterSubject"` // 主题关键词筛选
 	FilterSize    string `json:"filterSize"`    // 大小筛选：">1M", "<100K" 等
 	FilterRead    string `json:"filterRead"`    // 已读/未读：seen, unseen, all
+	// 筛选表达式，如 from:(a.com OR b.com) AND NOT subject:"invoice" AND larger:5M AND older:180d
+	// 与上面的筛选条件同时生效（AND）；设置后 EndDate 可以为空
+	FilterQuery string `json:"filterQuery"`
 	// 高级选项
 	EnableClientFallback bool `json:"enableClientFallback"` // 启用客户端回退（当服务端不支持发件人/主题搜索时）
+	// 删除方式
+	DeleteMode   DeleteMode `json:"deleteMode"`   // permanent, trash, folder，默认 permanent
+	TargetFolder string     `json:"targetFolder"` // 移动到指定文件夹时的目标文件夹
+	// 删除前备份
+	BackupBeforeDelete bool `json:"backupBeforeDelete"` // 删除前将邮件原文归档到本地
+	// 排除：预览后取消勾选的邮件或发件人，从匹配结果中去掉
+	Exclusions *CleanExclusions `json:"exclusions,omitempty"`
+	// 只能按清理计划执行：执行时只删除计划中的 UID，没有计划时不能用该请求重新搜索
+	PlanOnly bool `json:"planOnly,omitempty"`
+	// 保护：不删除带有这些标记的邮件（服务端 NOT 条件）
+	ProtectFlagged  bool     `json:"protectFlagged"`  // 跳过已加星标（\Flagged）的邮件
+	ProtectAnswered bool     `json:"protectAnswered"` // 跳过已回复（\Answered）的邮件
+	ProtectDraft    bool     `json:"protectDraft"`    // 跳过草稿（\Draft）
+	ProtectKeywords []string `json:"protectKeywords"` // 跳过带有这些关键字的邮件，如 $Important
+	// 动作：默认删除；strip_attachments 只移除附件，保留邮件正文
+	Action            CleanAction `json:"action"`
+	MinAttachmentSize int64       `json:"minAttachmentSize"` // 只移除不小于该大小的附件（字节，按编码后大小计算）
+}
+
+// ProtectedFlags 需要保护的 IMAP 标记，忽略空白和含非法字符的关键字
+func (r *CleanRequest) ProtectedFlags() []string {
+	var flags []string
+	if r.ProtectFlagged {
+		flags = append(flags, `\Flagged`)
+	}
+	if r.ProtectAnswered {
+		flags = append(flags, `\Answered`)
+	}
+	if r.ProtectDraft {
+		flags = append(flags, `\Draft`)
+	}
+	for _, keyword := range r.ProtectKeywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" || strings.ContainsAny(keyword, " (){%*\"\\]") {
+			continue
+		}
+		flags = append(flags, keyword)
+	}
+	return flags
+}
+
+// CleanExclusions 从匹配结果中排除的邮件
+type CleanExclusions struct {
+	UIDs    map[string][]uint32 `json:"uids,omitempty"`    // 按文件夹排除的 UID
+	Senders []string            `json:"senders,omitempty"` // 发件人地址（完整匹配，不区分大小写）
+	Domains []string            `json:"domains,omitempty"` // 发件人域名，同时排除子域名
+}
+
+// Normalize 统一发件人和域名的大小写，去掉空值和重复项
+func (e *CleanExclusions) Normalize() {
+	e.Senders = normalizeList(e.Senders, "")
+	e.Domains = normalizeList(e.Domains, "@")
+	for folder, uids := range e.UIDs {
+		if len(uids) == 0 {
+			delete(e.UIDs, folder)
+		}
+	}
+}
+
+// IsEmpty 是否没有任何排除项
+func (e *CleanExclusions) IsEmpty() bool {
+	return e == nil || (len(e.UIDs) == 0 && len(e.Senders) == 0 && len(e.Domains) == 0)
+}
+
+// FolderUIDs 文件夹中排除的 UID
+func (e *CleanExclusions) FolderUIDs(folder string) []uint32 {
+	if e == nil {
+		return nil
+	}
+	return e.UIDs[folder]
+}
+
+// HasSenderRules 是否有按发件人或域名排除的规则（需要获取邮件头才能判断）
+func (e *CleanExclusions) HasSenderRules() bool {
+	return e != nil && (len(e.Senders) > 0 || len(e.Domains) > 0)
+}
+
+// ExcludesSender 发件人地址是否被排除
+func (e *CleanExclusions) ExcludesSender(addr string) bool {
+	return e != nil && matchSender(addr, e.Senders, e.Domains)
+}
+
+// matchSender 发件人地址是否在地址列表中，或属于域名列表中的域名（含子域名）
+// 列表需已通过 normalizeList 处理
+func matchSender(addr string, senders, domains []string) bool {
+	if addr == "" {
+		return false
+	}
+	addr = strings.ToLower(addr)
+	for _, sender := range senders {
+		if addr == sender {
+			return true
+		}
+	}
+	_, domain, ok := strings.Cut(addr, "@")
+	if !ok {
+		return false
+	}
+	for _, d := range domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeList 转小写、去掉前缀 trimPrefix 和空白，并去重
+func normalizeList(items []string, trimPrefix string) []string {
+	var result []string
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		item = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(item)), trimPrefix)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		result = append(result, item)
+	}
+	return result
 }
 
 // GetBatchSize 获取批处理大小，使用默认值如果未设置
@@ -57,19 +190,63 @@ func (r *CleanRequest) GetMaxConcurrency() int {
 	return r.MaxConcurrency
 }
 
+// GetDeleteMode 获取删除方式，使用默认值如果未设置
+func (r *CleanRequest) GetDeleteMode() DeleteMode {
+	if r.DeleteMode == "" {
+		return DeleteModePermanent
+	}
+	return r.DeleteMode
+}
+
+// GetDateBasis 获取日期依据，使用默认值如果未设置
+func (r *CleanRequest) GetDateBasis() DateBasis {
+	if r.DateBasis == "" {
+		return DateBasisInternal
+	}
+	return r.DateBasis
+}
+
+// GetAction 获取清理动作，使用默认值如果未设置
+func (r *CleanRequest) GetAction() CleanAction {
+	if r.Action == "" {
+		return CleanActionDelete
+	}
+	return r.Action
+}
+
 // CleanProgress 清理进度
 type CleanProgress struct {
+	JobID          int64   `json:"jobId,omitempty"` // 所属的清理任务，由任务管理器填写
 	AccountID      int64   `json:"accountId"`
 	CurrentFolder  string  `json:"currentFolder"`
 	FolderIndex    int     `json:"folderIndex"`
 	TotalFolders   int     `json:"totalFolders"`
 	CurrentBatch   int     `json:"currentBatch"`
 	TotalBatches   int     `json:"totalBatches"`
-	DeletedCount   int     `json:"deletedCount"`
-	MatchedCount   int     `json:"matchedCount"`
-	Status         string  `json:"status"` // running, completed, failed, cancelled
+	DeletedCount   int     `json:"deletedCount"`        // 所有文件夹的合计
+	MatchedCount   int     `json:"matchedCount"`        // 所有文件夹的合计
+	BatchSize      int     `json:"batchSize,omitempty"` // 当前实际使用的批大小（自适应模式下会变化）
+	Status         string  `json:"status"`              // running, paused, completed, failed, cancelled
 	Message        string  `json:"message"`
 	ElapsedSeconds float64 `json:"elapsedSeconds"`
+
+	MessagesPerSecond float64          `json:"messagesPerSecond"`    // 最近的处理速度（封/秒）
+	ProcessedBytes    int64            `json:"processedBytes"`       // 已处理邮件的原始大小（字节）
+	ETASeconds        float64          `json:"etaSeconds,omitempty"` // 预计剩余时间（秒），0 表示无法估算
+	Folders           []FolderProgress `json:"folders,omitempty"`    // 各文件夹的进度快照，按文件夹顺序
+}
+
+// FolderProgress 单个文件夹的进度
+type FolderProgress struct {
+	Folder         string `json:"folder"`
+	FolderIndex    int    `json:"folderIndex"`
+	Status         string `json:"status"` // pending, running, paused, completed, skipped, failed, cancelled
+	MatchedCount   int    `json:"matchedCount"`
+	DeletedCount   int    `json:"deletedCount"`
+	ProcessedBytes int64  `json:"processedBytes"`
+	CurrentBatch   int    `json:"currentBatch,omitempty"`
+	TotalBatches   int    `json:"totalBatches,omitempty"`
+	Error          string `json:"error,omitempty"`
 }
 
 // CleanResult 清理结果
@@ -80,14 +257,32 @@ type CleanResult struct {
 	Duration     float64         `json:"duration"`
 	Status       string          `json:"status"`
 	Error        string          `json:"error,omitempty"`
+	ArchivePath  string          `json:"archivePath,omitempty"` // 删除前备份的归档目录
+	AttachmentDir  string        `json:"attachmentDir,omitempty"`  // 移除附件时附件的保存目录
+	ReclaimedBytes int64         `json:"reclaimedBytes,omitempty"` // 移除附件释放的空间（字节）
+	ProcessedBytes int64         `json:"processedBytes,omitempty"` // 已处理邮件的原始大小（字节）
+	PlanID       int64           `json:"planId,omitempty"`      // 预览生成的清理计划，可通过 ExecutePlan 删除预览中的邮件
+}
+
+// CleanErrorEvent 清理失败事件，带任务和账号 ID，前端据此区分同时进行的清理
+type CleanErrorEvent struct {
+	JobID     int64  `json:"jobId"`
+	AccountID int64  `json:"accountId"`
+	Error     string `json:"error"`
 }
 
 // FolderCleanStat 文件夹清理统计
 type FolderCleanStat struct {
-	Folder       string `json:"folder"`
-	MatchedCount int    `json:"matchedCount"`
-	DeletedCount int    `json:"deletedCount"`
-	Status       string `json:"status"`
-	Error        string `json:"error,omitempty"`
+	Folder         string      `json:"folder"`
+	MatchedCount   int         `json:"matchedCount"`
+	DeletedCount   int         `json:"deletedCount"`
+	ExcludedCount  int         `json:"excludedCount,omitempty"`  // 被排除项去掉的邮件数（不计入 MatchedCount）
+	ProtectedCount int         `json:"protectedCount,omitempty"` // 受保护而跳过的邮件数（不计入 MatchedCount）
+	ReclaimedBytes int64       `json:"reclaimedBytes,omitempty"` // 移除附件释放的空间（字节）
+	ProcessedBytes int64       `json:"processedBytes,omitempty"` // 已处理（删除、移动或移除附件）邮件的原始大小（字节）
+	Status         string      `json:"status"`
+	Error          string      `json:"error,omitempty"`
+	ExpungeMode    ExpungeMode `json:"expungeMode,omitempty"` // 实际使用的清除方式
+	Warning        string      `json:"warning,omitempty"`     // 风险提示（如不支持 UIDPLUS）
 }
 
@@ -4,25 +4,30 @@ import "time"
 
 // CleanHistory 清理历史记录
 type CleanHistory struct {
-	ID            int64     `json:"id"`
-	AccountID     int64     `json:"accountId"`
-	AccountEmail  string    `json:"accountEmail"`
-	Folders       string    `json:"folders"`       // JSON 数组
-	FolderCount   int       `json:"folderCount"`
-	DateRange     string    `json:"dateRange"`     // 如 "2024-01-01 ~ 2024-06-01"
-	FilterSender  string    `json:"filterSender"`  // 发件人筛选
-	FilterSubject string    `json:"filterSubject"` // 主题筛选
-	FilterSize    string    `json:"filterSize"`    // 大小筛选
-	FilterRead    string    `json:"filterRead"`    // 已读/未读筛选
-	MatchedCount  int       `json:"matchedCount"`
-	DeletedCount  int       `json:"deletedCount"`
-	PreviewOnly   bool      `json:"previewOnly"`
-	StartTime     time.Time `json:"startTime"`
-	EndTime       time.Time `json:"endTime"`
-	Duration      float64   `json:"duration"` // 秒
-	Status        string    `json:"status"`   // running, completed, failed, cancelled
-	ErrorMessage  string    `json:"errorMessage,omitempty"`
-	CreatedAt     time.Time `json:"createdAt"`
+	ID            int64            `json:"id"`
+	AccountID     int64            `json:"accountId"`
+	AccountEmail  string           `json:"accountEmail"`
+	Folders       string           `json:"folders"` // JSON 数组
+	FolderCount   int              `json:"folderCount"`
+	DateRange     string           `json:"dateRange"`          // 如 "2024-01-01 ~ 2024-06-01"，使用相对日期时为解析后的日期
+	DateExpr      string           `json:"dateExpr,omitempty"` // 相对日期表达式，如 "olderThan: 90d"
+	FilterSender  string           `json:"filterSender"`       // 发件人筛选
+	FilterSubject string           `json:"filterSubject"`      // 主题筛选
+	FilterSize    string           `json:"filterSize"`         // 大小筛选
+	FilterRead    string           `json:"filterRead"`         // 已读/未读筛选
+	FilterQuery   string           `json:"filterQuery"`        // 筛选表达式
+	MatchedCount  int              `json:"matchedCount"`
+	DeletedCount  int              `json:"deletedCount"`
+	PreviewOnly   bool             `json:"previewOnly"`
+	StartTime     time.Time        `json:"startTime"`
+	EndTime       time.Time        `json:"endTime"`
+	Duration      float64          `json:"duration"` // 秒
+	Status        string           `json:"status"`   // running, paused, completed, failed, cancelled, interrupted
+	ErrorMessage  string           `json:"errorMessage,omitempty"`
+	ArchivePath   string           `json:"archivePath,omitempty"` // 删除前备份的归档目录
+	ScheduleID    int64            `json:"scheduleId,omitempty"`  // 由定时清理计划触发时对应的计划
+	Exclusions    *CleanExclusions `json:"exclusions,omitempty"`  // 排除的邮件、发件人和域名
+	CreatedAt     time.Time        `json:"createdAt"`
 }
 
 // CleanHistoryListItem 历史记录列表项（简化版）
@@ -31,11 +36,64 @@ type CleanHistoryListItem struct {
 	AccountEmail string    `json:"accountEmail"`
 	FolderCount  int       `json:"folderCount"`
 	DateRange    string    `json:"dateRange"`
+	DateExpr     string    `json:"dateExpr,omitempty"`
 	MatchedCount int       `json:"matchedCount"`
 	DeletedCount int       `json:"deletedCount"`
 	PreviewOnly  bool      `json:"previewOnly"`
 	Duration     float64   `json:"duration"`
 	Status       string    `json:"status"`
+	ScheduleID   int64     `json:"scheduleId,omitempty"`
 	CreatedAt    time.Time `json:"createdAt"`
 }
 
+// CleanCheckpoint 清理检查点，记录文件夹的删除进度，用于中断后恢复
+type CleanCheckpoint struct {
+	HistoryID     int64     `json:"historyId"`
+	Folder        string    `json:"folder"`
+	UIDValidity   uint32    `json:"uidValidity"`
+	RemainingUIDs string    `json:"remainingUids"` // 尚未删除的 UID 集合，如 "1:100,205"
+	StrippedUIDs  string    `json:"strippedUids"`  // 移除附件时已上传去附件副本、原邮件尚未删除的 UID，继续时不再重复上传
+	MatchedCount  int       `json:"matchedCount"`
+	DeletedCount  int       `json:"deletedCount"`
+	Status        string    `json:"status"` // running, completed
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// InterruptedClean 因应用退出而中断的清理任务
+type InterruptedClean struct {
+	ID               int64     `json:"id"`
+	AccountID        int64     `json:"accountId"`
+	AccountEmail     string    `json:"accountEmail"`
+	FolderCount      int       `json:"folderCount"`
+	CompletedFolders int       `json:"completedFolders"` // 检查点中已完成的文件夹数
+	DeletedCount     int       `json:"deletedCount"`     // 检查点中已删除的邮件数
+	StartTime        time.Time `json:"startTime"`
+	Resumable        bool      `json:"resumable"` // 是否保存了原始请求，可以继续执行
+}
+
+// RestoreProgress 归档恢复进度
+type RestoreProgress struct {
+	HistoryID      int64   `json:"historyId"`
+	CurrentFolder  string  `json:"currentFolder"`
+	FolderIndex    int     `json:"folderIndex"`
+	TotalFolders   int     `json:"totalFolders"`
+	TotalCount     int     `json:"totalCount"`
+	RestoredCount  int     `json:"restoredCount"`
+	SkippedCount   int     `json:"skippedCount"` // Message-ID 已存在而跳过的邮件数
+	FailedCount    int     `json:"failedCount"`
+	Status         string  `json:"status"` // running, completed, failed, cancelled
+	Message        string  `json:"message"`
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+}
+
+// RestoreResult 归档恢复结果
+type RestoreResult struct {
+	HistoryID     int64   `json:"historyId"`
+	TotalCount    int     `json:"totalCount"`
+	RestoredCount int     `json:"restoredCount"`
+	SkippedCount  int     `json:"skippedCount"`
+	FailedCount   int     `json:"failedCount"`
+	Duration      float64 `json:"duration"`
+	Status        string  `json:"status"`
+	Error         string  `json:"error,omitempty"`
+}
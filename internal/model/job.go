@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// JobKind 清理任务的来源
+type JobKind string
+
+const (
+	JobKindClean    JobKind = "clean"    // 手动开始的清理或预览
+	JobKindPlan     JobKind = "plan"     // 执行清理计划
+	JobKindResume   JobKind = "resume"   // 继续中断的清理
+	JobKindSchedule JobKind = "schedule" // 定时清理
+)
+
+// CleanJob 清理任务，由任务管理器按 ID 管理，不同账号的任务可以同时执行
+type CleanJob struct {
+	ID           int64          `json:"id"`
+	Kind         JobKind        `json:"kind"`
+	AccountID    int64          `json:"accountId"`
+	AccountEmail string         `json:"accountEmail"`
+	HistoryID    int64          `json:"historyId,omitempty"`
+	PlanID       int64          `json:"planId,omitempty"`
+	ScheduleID   int64          `json:"scheduleId,omitempty"`
+	PreviewOnly  bool           `json:"previewOnly"`
+	Status       string         `json:"status"`             // running, paused, completed, failed, cancelled
+	Progress     *CleanProgress `json:"progress,omitempty"` // 最近一次进度
+	Result       *CleanResult   `json:"result,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	StartedAt    time.Time      `json:"startedAt"`
+	FinishedAt   *time.Time     `json:"finishedAt,omitempty"`
+}
+
+// Active 任务是否尚未结束（执行中或已暂停）
+func (j *CleanJob) Active() bool {
+	return j.Status == "running" || j.Status == "paused"
+}
+
+// Destructive 是否会删除或移动邮件（同一账号同时只允许一个）
+func (j *CleanJob) Destructive() bool {
+	return !j.PreviewOnly
+}
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// CleanPlan 状态
+const (
+	PlanStatusPending  = "pending"  // 等待执行
+	PlanStatusExecuted = "executed" // 已执行（无论成功与否，计划只能执行一次）
+)
+
+// CleanPlan 清理计划：预览时确定的待删除邮件，执行时只删除这些 UID
+type CleanPlan struct {
+	ID                int64              `json:"id"`
+	AccountID         int64              `json:"accountId"`
+	AccountEmail      string             `json:"accountEmail"`
+	PreviewHistoryID  int64              `json:"previewHistoryId"` // 生成计划的预览历史记录
+	Request           CleanRequest       `json:"request"`          // 预览时的清理请求（删除方式、备份等执行时沿用）
+	Folders           []*CleanPlanFolder `json:"folders"`
+	TotalCount        int                `json:"totalCount"`
+	Status            string             `json:"status"`
+	ExecutedHistoryID int64              `json:"executedHistoryId,omitempty"` // 执行计划的清理历史记录
+	ExpiresAt         time.Time          `json:"expiresAt"`                   // 超过该时间计划失效，需要重新预览
+	CreatedAt         time.Time          `json:"createdAt"`
+}
+
+// CleanPlanFolder 计划中单个文件夹的待删除邮件
+type CleanPlanFolder struct {
+	Folder      string `json:"folder"`
+	UIDValidity uint32 `json:"uidValidity"` // 预览时的 UIDVALIDITY，执行时不一致则拒绝执行
+	UIDs        string `json:"uids"`        // UID 集合，如 "1:100,205"
+	Count       int    `json:"count"`
+}
+
+// IsStale 计划是否已失效（已执行或已过期）
+func (p *CleanPlan) IsStale(now time.Time) bool {
+	return p.Status != PlanStatusPending || now.After(p.ExpiresAt)
+}
+
+// ExecuteRequest 执行计划使用的清理请求：只包含计划中的文件夹，并标记为只能按计划执行
+func (p *CleanPlan) ExecuteRequest() CleanRequest {
+	req := p.Request
+	req.Folders = p.FolderNames()
+	req.PlanOnly = true
+	return req
+}
+
+// FolderNames 计划涉及的文件夹
+func (p *CleanPlan) FolderNames() []string {
+	names := make([]string, len(p.Folders))
+	for i, f := range p.Folders {
+		names[i] = f.Folder
+	}
+	return names
+}
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// CleanPreset 清理条件预设，保存常用的文件夹和筛选条件，可应用到任意账号
+type CleanPreset struct {
+	ID             int64     `json:"id"`
+	Name           string    `json:"name"`
+	Folders        []string  `json:"folders"`
+	FilterSender   string    `json:"filterSender"`
+	FilterSubject  string    `json:"filterSubject"`
+	FilterSize     string    `json:"filterSize"`
+	FilterRead     string    `json:"filterRead"`
+	FilterQuery    string    `json:"filterQuery"`
+	OlderThan      string    `json:"olderThan"` // 相对日期，应用时原样带入清理请求，执行时再解析
+	Between        string    `json:"between"`
+	DateBasis      DateBasis `json:"dateBasis"` // internal 或 sent，为空时按 internal
+	BatchSize      int       `json:"batchSize"`
+	AdaptiveBatch  bool      `json:"adaptiveBatch"`
+	MaxConcurrency int       `json:"maxConcurrency"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// ApplyTo 将预设应用到指定账号，生成清理请求（未设置相对日期时，日期由调用方填写；删除方式由调用方填写）
+func (p *CleanPreset) ApplyTo(accountID int64) *CleanRequest {
+	return &CleanRequest{
+		AccountID:      accountID,
+		Folders:        append([]string(nil), p.Folders...),
+		FilterSender:   p.FilterSender,
+		FilterSubject:  p.FilterSubject,
+		FilterSize:     p.FilterSize,
+		FilterRead:     p.FilterRead,
+		FilterQuery:    p.FilterQuery,
+		OlderThan:      p.OlderThan,
+		Between:        p.Between,
+		DateBasis:      p.DateBasis,
+		BatchSize:      p.BatchSize,
+		AdaptiveBatch:  p.AdaptiveBatch,
+		MaxConcurrency: p.MaxConcurrency,
+	}
+}
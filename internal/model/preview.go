@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// PreviewMessage 预览中符合条件的单封邮件
+type PreviewMessage struct {
+	UID     uint32    `json:"uid"`
+	Folder  string    `json:"folder"`
+	From    string    `json:"from"`
+	Subject string    `json:"subject"`
+	Date    time.Time `json:"date"` // 邮件头日期，缺失时使用服务器接收时间
+	Size    int64     `json:"size"`
+	Flags   []string  `json:"flags"`
+}
+
+// PreviewSortField 预览列表排序字段
+type PreviewSortField string
+
+const (
+	PreviewSortDate    PreviewSortField = "date"
+	PreviewSortSize    PreviewSortField = "size"
+	PreviewSortFrom    PreviewSortField = "from"
+	PreviewSortSubject PreviewSortField = "subject"
+	PreviewSortFolder  PreviewSortField = "folder"
+)
+
+// PreviewQuery 预览列表分页查询
+type PreviewQuery struct {
+	AccountID int64            `json:"accountId"`
+	Folder    string           `json:"folder"`   // 只看某个文件夹，为空时不限
+	Page      int              `json:"page"`     // 从 1 开始
+	PageSize  int              `json:"pageSize"` // 默认 50
+	SortBy    PreviewSortField `json:"sortBy"`   // 默认 date
+	SortDesc  bool             `json:"sortDesc"`
+}
+
+// PreviewPage 预览列表的一页
+type PreviewPage struct {
+	AccountID int64             `json:"accountId"`
+	HistoryID int64             `json:"historyId"` // 对应的预览历史记录
+	CreatedAt time.Time         `json:"createdAt"`
+	Total     int               `json:"total"`
+	TotalSize int64             `json:"totalSize"`
+	Page      int               `json:"page"`
+	PageSize  int               `json:"pageSize"`
+	Messages  []*PreviewMessage `json:"messages"`
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScheduleType 定时清理的调度方式
+type ScheduleType string
+
+const (
+	ScheduleTypeCron     ScheduleType = "cron"     // cron 表达式，如 "0 3 1 * *"（每月 1 日 03:00）
+	ScheduleTypeInterval ScheduleType = "interval" // 固定间隔，如 "24h"、"168h"
+)
+
+// CleanSchedule 定时清理计划
+type CleanSchedule struct {
+	ID           int64        `json:"id"`
+	Name         string       `json:"name"`
+	AccountID    int64        `json:"accountId"`
+	AccountEmail string       `json:"accountEmail"`
+	ScheduleType ScheduleType `json:"scheduleType"`
+	Spec         string       `json:"spec"` // cron 表达式或间隔时长
+	// Request 清理请求模板，StartDate/EndDate 在每次执行时按相对日期重新计算
+	// 模板设置了 OlderThan/Between 时按表达式计算，否则按 OlderThanDays/WindowDays 计算
+	Request CleanRequest `json:"request"`
+	// 相对日期：清理 OlderThanDays 天之前的邮件；WindowDays > 0 时只清理该天数范围内的邮件
+	// 模板未设置 OlderThan/Between 时 OlderThanDays 必须大于 0，避免无人值守时删除截至当天的所有邮件
+	OlderThanDays int        `json:"olderThanDays"`
+	WindowDays    int        `json:"windowDays"`
+	Enabled       bool       `json:"enabled"`
+	NextRunAt     *time.Time `json:"nextRunAt"`
+	LastRunAt     *time.Time `json:"lastRunAt"`
+	LastStatus    string     `json:"lastStatus"`    // 最近一次执行状态：completed, failed, cancelled
+	LastHistoryID int64      `json:"lastHistoryId"` // 最近一次执行对应的清理历史
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// ResolveRequest 按执行时间（用户时区）计算相对日期，生成本次执行的清理请求
+// OlderThanDays/WindowDays 转换为等价的 OlderThan/Between 表达式，与手动清理使用同一套日期计算
+func (s *CleanSchedule) ResolveRequest(now time.Time) (*CleanRequest, error) {
+	req := s.Request
+	req.AccountID = s.AccountID
+	req.Folders = append([]string(nil), s.Request.Folders...)
+
+	if !req.HasRelativeDate() {
+		if s.OlderThanDays <= 0 {
+			return nil, fmt.Errorf("请设置清理多少天之前的邮件（天数须大于 0）或相对日期表达式")
+		}
+		if s.WindowDays > 0 {
+			req.Between = fmt.Sprintf("%dd..%dd", s.OlderThanDays+s.WindowDays, s.OlderThanDays)
+		} else {
+			req.OlderThan = fmt.Sprintf("%dd", s.OlderThanDays)
+		}
+	}
+	if err := req.ResolveDates(now); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// ScheduleRunEvent 定时清理执行事件
+type ScheduleRunEvent struct {
+	ScheduleID   int64        `json:"scheduleId"`
+	ScheduleName string       `json:"scheduleName"`
+	HistoryID    int64        `json:"historyId"`
+	Status       string       `json:"status"` // running, completed, failed, cancelled
+	Result       *CleanResult `json:"result,omitempty"`
+	Error        string       `json:"error,omitempty"`
+}
@@ -1,5 +1,10 @@
 package model
 
+import (
+	"fmt"
+	"time"
+)
+
 // ProxyType 代理类型
 type ProxyType string
 
@@ -59,9 +64,81 @@ func itoa(n int) string {
 	return string(digits)
 }
 
+// ProtectedSenders 受保护的发件人，任何清理都不会删除这些发件人的邮件
+type ProtectedSenders struct {
+	Addresses []string `json:"addresses"` // 发件人地址（完整匹配，不区分大小写）
+	Domains   []string `json:"domains"`   // 发件人域名，同时保护子域名
+}
+
+// Normalize 统一大小写，去掉空值和重复项
+func (p *ProtectedSenders) Normalize() {
+	p.Addresses = normalizeList(p.Addresses, "")
+	p.Domains = normalizeList(p.Domains, "@")
+}
+
+// IsEmpty 是否没有受保护的发件人
+func (p *ProtectedSenders) IsEmpty() bool {
+	return p == nil || (len(p.Addresses) == 0 && len(p.Domains) == 0)
+}
+
+// Protects 发件人地址是否受保护
+func (p *ProtectedSenders) Protects(addr string) bool {
+	return p != nil && matchSender(addr, p.Addresses, p.Domains)
+}
+
+// RateLimit IMAP 命令限速（令牌桶），同一服务器主机的所有连接共享
+type RateLimit struct {
+	CommandsPerSecond float64 `json:"commandsPerSecond"` // 每秒允许的命令数，0 表示不限速
+	Burst             int     `json:"burst"`             // 突发容量（连续执行的最大命令数）
+}
+
+// Validate 校验限速配置
+func (r RateLimit) Validate() error {
+	if r.CommandsPerSecond < 0 || r.Burst < 0 {
+		return fmt.Errorf("限速不能为负数")
+	}
+	if r.CommandsPerSecond > 0 && r.Burst < 1 {
+		return fmt.Errorf("突发容量至少为 1")
+	}
+	return nil
+}
+
+// VendorRateLimit 厂商的限速设置（设置界面使用）
+type VendorRateLimit struct {
+	Vendor  EmailVendorType `json:"vendor"`
+	Name    string          `json:"name"`
+	Limit   RateLimit       `json:"limit"`   // 实际使用的限速
+	Default RateLimit       `json:"default"` // 默认限速
+	Custom  bool            `json:"custom"`  // 是否为用户设置
+}
+
 // AppSettings 应用全局设置
 type AppSettings struct {
-	Proxy ProxySettings `json:"proxy"`
+	Proxy            ProxySettings    `json:"proxy"`
+	ProtectedSenders ProtectedSenders `json:"protectedSenders"`
+	Timezone         string           `json:"timezone"` // IANA 时区名，如 Asia/Shanghai，为空时使用系统时区
+	// 用户覆盖的限速，按邮箱厂商，未设置的厂商使用 EmailVendorType.DefaultRateLimit
+	RateLimits map[EmailVendorType]RateLimit `json:"rateLimits,omitempty"`
+}
+
+// RateLimitFor 获取厂商的限速：用户设置优先，否则使用默认值
+func (s *AppSettings) RateLimitFor(vendor EmailVendorType) RateLimit {
+	if limit, ok := s.RateLimits[vendor]; ok {
+		return limit
+	}
+	return vendor.DefaultRateLimit()
+}
+
+// Location 用户设置的时区，未设置或无法识别时使用系统时区
+func (s *AppSettings) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
 }
 
 // DefaultAppSettings 默认设置
@@ -0,0 +1,384 @@
+// Package scheduler 定时清理：按 cron 表达式或固定间隔执行保存的清理请求
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"CleanMyEmail/internal/account"
+	"CleanMyEmail/internal/archive"
+	"CleanMyEmail/internal/db"
+	"CleanMyEmail/internal/email/cleaner"
+	"CleanMyEmail/internal/email/imap"
+	"CleanMyEmail/internal/email/query"
+	"CleanMyEmail/internal/job"
+	"CleanMyEmail/internal/model"
+	"CleanMyEmail/internal/service"
+)
+
+const checkInterval = 30 * time.Second // 检查到期计划的间隔
+
+// EventHandler 定时清理事件回调，event 为 schedule:start / schedule:complete
+type EventHandler func(event string, data *model.ScheduleRunEvent)
+
+// Scheduler 定时清理调度器，应用（或守护进程）运行期间按计划执行清理
+type Scheduler struct {
+	accountService *account.Service
+	historyService *service.HistoryService
+	auditService   *service.AuditService
+	poolManager    *imap.PoolManager
+	jobManager     *job.Manager
+
+	mu       sync.Mutex
+	running  map[int64]*cleaner.Cleaner // 正在执行的计划，key: scheduleID
+	onEvent  EventHandler
+	started  bool
+	stopCh   chan struct{}
+	loopDone chan struct{}
+	runs     sync.WaitGroup
+}
+
+// NewScheduler 创建调度器
+// 定时清理作为清理任务交给 jobManager 执行，与手动开始的清理共用同一账号的互斥检查
+func NewScheduler(accountService *account.Service, historyService *service.HistoryService, poolManager *imap.PoolManager, jobManager *job.Manager) *Scheduler {
+	return &Scheduler{
+		accountService: accountService,
+		historyService: historyService,
+		auditService:   service.NewAuditService(),
+		poolManager:    poolManager,
+		jobManager:     jobManager,
+		running:        make(map[int64]*cleaner.Cleaner),
+	}
+}
+
+// SetEventHandler 设置执行事件回调
+func (s *Scheduler) SetEventHandler(handler EventHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onEvent = handler
+}
+
+// Start 启动调度循环，错过的计划（应用未运行期间到期）会立即执行一次
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	s.stopCh = make(chan struct{})
+	s.loopDone = make(chan struct{})
+	go s.loop()
+}
+
+// Stop 停止调度循环，取消正在执行的清理并等待其结束
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = false
+	close(s.stopCh)
+	for _, c := range s.running {
+		c.Cancel()
+	}
+	s.mu.Unlock()
+
+	<-s.loopDone
+	s.runs.Wait()
+}
+
+// loop 定期检查到期的计划
+func (s *Scheduler) loop() {
+	defer close(s.loopDone)
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	s.runDue()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.runDue()
+		}
+	}
+}
+
+// runDue 执行所有到期的计划
+func (s *Scheduler) runDue() {
+	now := time.Now()
+	schedules, err := db.ListDueSchedules(now)
+	if err != nil {
+		log.Printf("[WARN] 获取到期的定时清理失败: %v", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		// 先推进下次执行时间，避免执行失败或应用崩溃后反复触发
+		next, err := NextRun(schedule.ScheduleType, schedule.Spec, now)
+		var nextRunAt *time.Time
+		if err != nil {
+			log.Printf("[WARN] 定时清理 %q 计算下次执行时间失败，已停止调度: %v", schedule.Name, err)
+		} else {
+			nextRunAt = &next
+		}
+		if err := db.UpdateScheduleNextRun(schedule.ID, nextRunAt); err != nil {
+			log.Printf("[WARN] 更新定时清理 %q 下次执行时间失败: %v", schedule.Name, err)
+			continue
+		}
+		s.launch(schedule)
+	}
+}
+
+// RunNow 立即执行一次计划（不影响下次执行时间）
+func (s *Scheduler) RunNow(id int64) error {
+	schedule, err := db.GetSchedule(id)
+	if err != nil {
+		return fmt.Errorf("获取定时清理失败: %w", err)
+	}
+	if !s.launch(schedule) {
+		return fmt.Errorf("定时清理 %q 正在执行中", schedule.Name)
+	}
+	return nil
+}
+
+// IsRunning 计划是否正在执行
+func (s *Scheduler) IsRunning(id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.running[id]
+	return ok
+}
+
+// launch 异步执行计划，同一计划不会并发执行
+func (s *Scheduler) launch(schedule *model.CleanSchedule) bool {
+	s.mu.Lock()
+	if _, ok := s.running[schedule.ID]; ok {
+		s.mu.Unlock()
+		log.Printf("[INFO] 定时清理 %q 上一次执行尚未结束，跳过本次", schedule.Name)
+		return false
+	}
+	s.running[schedule.ID] = nil // 占位，清理器创建后替换
+	s.runs.Add(1)
+	s.mu.Unlock()
+
+	go func() {
+		defer s.runs.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, schedule.ID)
+			s.mu.Unlock()
+		}()
+		s.execute(schedule)
+	}()
+	return true
+}
+
+// execute 执行一次计划：按相对日期生成请求，经连接池管理器和清理器执行，并记录到清理历史
+func (s *Scheduler) execute(schedule *model.CleanSchedule) {
+	runAt := time.Now()
+	event := &model.ScheduleRunEvent{ScheduleID: schedule.ID, ScheduleName: schedule.Name, Status: "running"}
+
+	finish := func(status, errMsg string, result *model.CleanResult) {
+		event.Status = status
+		event.Error = errMsg
+		event.Result = result
+		if err := db.UpdateScheduleLastRun(schedule.ID, runAt, status, event.HistoryID); err != nil {
+			log.Printf("[WARN] 记录定时清理 %q 执行结果失败: %v", schedule.Name, err)
+		}
+		s.emit("schedule:complete", event)
+	}
+
+	req, err := schedule.ResolveRequest(runAt.In(db.GetLocation()))
+	if err != nil {
+		finish("failed", err.Error(), nil)
+		return
+	}
+	log.Printf("[INFO] 开始执行定时清理 %q（账号 %d，%s 之前）", schedule.Name, schedule.AccountID, req.EndDate)
+
+	acc, err := s.accountService.Get(req.AccountID)
+	if err != nil {
+		finish("failed", fmt.Sprintf("获取账号失败: %v", err), nil)
+		return
+	}
+	cfg, err := s.accountService.GetConnectConfig(req.AccountID)
+	if err != nil {
+		finish("failed", err.Error(), nil)
+		return
+	}
+	if err := s.jobManager.CheckAvailable(req.AccountID, !req.PreviewOnly); err != nil {
+		log.Printf("[INFO] 定时清理 %q 跳过: %v", schedule.Name, err)
+		finish("failed", err.Error(), nil)
+		return
+	}
+
+	historyID, err := s.historyService.CreateHistory(req, acc.Email)
+	if err != nil {
+		log.Printf("[WARN] 创建历史记录失败: %v", err)
+	} else if err := s.historyService.SetScheduleID(historyID, schedule.ID); err != nil {
+		log.Printf("[WARN] 记录历史关联的定时清理失败: %v", err)
+	}
+	event.HistoryID = historyID
+
+	pool := s.poolManager.GetPool(req.AccountID, cfg, &imap.PoolOptions{
+		MaxSize:     req.GetMaxConcurrency(),
+		IdleTimeout: 5 * time.Minute,
+	})
+	c := cleaner.NewCleaner(pool)
+	if req.BackupBeforeDelete && !req.PreviewOnly {
+		archiveDir := archive.NewRunDir(req.AccountID)
+		c.SetArchiveDir(archiveDir)
+		if historyID > 0 {
+			if err := s.historyService.SetArchivePath(historyID, archiveDir); err != nil {
+				log.Printf("[WARN] 记录归档目录失败: %v", err)
+			}
+		}
+	}
+	if historyID > 0 && !req.PreviewOnly {
+		c.EnableCheckpoint(historyID, s.historyService)
+		c.EnableAudit(historyID, acc.Email, s.auditService)
+	}
+
+	s.mu.Lock()
+	stopped := !s.started
+	s.running[schedule.ID] = c
+	s.mu.Unlock()
+	if stopped {
+		c.Cancel()
+	}
+
+	s.emit("schedule:start", event)
+
+	result, err := s.jobManager.Run(&job.Task{
+		Job: &model.CleanJob{
+			Kind:         model.JobKindSchedule,
+			AccountID:    req.AccountID,
+			AccountEmail: acc.Email,
+			HistoryID:    historyID,
+			ScheduleID:   schedule.ID,
+			PreviewOnly:  req.PreviewOnly,
+		},
+		Cleaner: c,
+		Request: req,
+	})
+	if err != nil {
+		if historyID > 0 {
+			s.historyService.UpdateHistory(historyID, 0, 0, "failed", err.Error(), 0)
+			s.historyService.DeleteCheckpoints(historyID)
+		}
+		log.Printf("[WARN] 定时清理 %q 执行失败: %v", schedule.Name, err)
+		finish("failed", err.Error(), nil)
+		return
+	}
+
+	if historyID > 0 {
+		matchedCount := 0
+		for _, stat := range result.FolderStats {
+			matchedCount += stat.MatchedCount
+		}
+		s.historyService.UpdateHistory(historyID, matchedCount, result.TotalDeleted, result.Status, "", result.Duration)
+		s.historyService.DeleteCheckpoints(historyID)
+	}
+	log.Printf("[INFO] 定时清理 %q 执行%s，共删除 %d 封邮件", schedule.Name, result.Status, result.TotalDeleted)
+	finish(result.Status, "", result)
+}
+
+// emit 发送执行事件
+func (s *Scheduler) emit(event string, data *model.ScheduleRunEvent) {
+	s.mu.Lock()
+	handler := s.onEvent
+	s.mu.Unlock()
+	if handler != nil {
+		handler(event, data)
+	}
+}
+
+// Validate 校验计划配置并计算下次执行时间
+func Validate(schedule *model.CleanSchedule, now time.Time) error {
+	schedule.Name = strings.TrimSpace(schedule.Name)
+	schedule.Spec = strings.TrimSpace(schedule.Spec)
+	if schedule.Name == "" {
+		return fmt.Errorf("请输入计划名称")
+	}
+	if schedule.AccountID <= 0 {
+		return fmt.Errorf("请选择账号")
+	}
+	if len(schedule.Request.Folders) == 0 {
+		return fmt.Errorf("请选择要清理的文件夹")
+	}
+	if _, err := query.Parse(schedule.Request.FilterQuery, db.GetLocation()); err != nil {
+		return fmt.Errorf("筛选表达式错误: %w", err)
+	}
+	if schedule.OlderThanDays < 0 || schedule.WindowDays < 0 {
+		return fmt.Errorf("相对日期不能为负数")
+	}
+	// 未设置相对日期表达式时要求 OlderThanDays > 0，不允许无人值守地删除截至当天的所有邮件
+	if _, err := schedule.ResolveRequest(now); err != nil {
+		return err
+	}
+	switch schedule.Request.GetDateBasis() {
+	case model.DateBasisInternal, model.DateBasisSent:
+	default:
+		return fmt.Errorf("不支持的日期依据: %s", schedule.Request.DateBasis)
+	}
+	if schedule.Request.GetDeleteMode() == model.DeleteModeFolder && schedule.Request.TargetFolder == "" {
+		return fmt.Errorf("请指定目标文件夹")
+	}
+
+	// 停用的计划也校验表达式，但不设置下次执行时间
+	next, err := NextRun(schedule.ScheduleType, schedule.Spec, now)
+	if err != nil {
+		return err
+	}
+	schedule.NextRunAt = nil
+	if schedule.Enabled {
+		schedule.NextRunAt = &next
+	}
+	return nil
+}
+
+// Save 创建或更新计划（ID 为 0 时创建）
+func (s *Scheduler) Save(schedule *model.CleanSchedule) (*model.CleanSchedule, error) {
+	if err := Validate(schedule, time.Now()); err != nil {
+		return nil, err
+	}
+	schedule.Request.AccountID = schedule.AccountID
+
+	if schedule.ID == 0 {
+		id, err := db.CreateSchedule(schedule)
+		if err != nil {
+			return nil, fmt.Errorf("创建定时清理失败: %w", err)
+		}
+		schedule.ID = id
+	} else if err := db.UpdateSchedule(schedule); err != nil {
+		return nil, fmt.Errorf("更新定时清理失败: %w", err)
+	}
+	return db.GetSchedule(schedule.ID)
+}
+
+// SetEnabled 启用或停用计划，启用时从当前时间重新计算下次执行时间
+func (s *Scheduler) SetEnabled(id int64, enabled bool) error {
+	schedule, err := db.GetSchedule(id)
+	if err != nil {
+		return fmt.Errorf("获取定时清理失败: %w", err)
+	}
+	schedule.Enabled = enabled
+	_, err = s.Save(schedule)
+	return err
+}
+
+// Delete 删除计划，正在执行的清理会被取消
+func (s *Scheduler) Delete(id int64) error {
+	s.mu.Lock()
+	if c := s.running[id]; c != nil {
+		c.Cancel()
+	}
+	s.mu.Unlock()
+	return db.DeleteSchedule(id)
+}
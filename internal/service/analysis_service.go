@@ -0,0 +1,64 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"CleanMyEmail/internal/db"
+	"CleanMyEmail/internal/model"
+)
+
+// AnalysisService 邮箱分析报告服务
+type AnalysisService struct{}
+
+// NewAnalysisService 创建邮箱分析报告服务
+func NewAnalysisService() *AnalysisService {
+	return &AnalysisService{}
+}
+
+// Save 保存分析报告
+func (s *AnalysisService) Save(report *model.AnalysisReport, accountEmail string) (*model.AnalysisReport, error) {
+	report.AccountEmail = accountEmail
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now()
+	}
+	id, err := db.CreateAnalysisReport(report)
+	if err != nil {
+		return nil, fmt.Errorf("保存分析报告失败: %w", err)
+	}
+	report.ID = id
+	return report, nil
+}
+
+// Get 获取分析报告
+func (s *AnalysisService) Get(id int64) (*model.AnalysisReport, error) {
+	report, err := db.GetAnalysisReport(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("分析报告不存在")
+	}
+	return report, err
+}
+
+// List 获取分析报告列表，accountID 为 0 时返回所有账号的报告
+func (s *AnalysisService) List(accountID int64) ([]*model.AnalysisListItem, error) {
+	return db.ListAnalysisReports(accountID)
+}
+
+// Delete 删除分析报告
+func (s *AnalysisService) Delete(id int64) error {
+	return db.DeleteAnalysisReport(id)
+}
+
+// CleanRequestFor 将报告中的一行（发件人、域名、月份或文件夹）转换为预览模式的清理请求
+func (s *AnalysisService) CleanRequestFor(reportID int64, dimension model.AnalysisDimension, key string) (*model.CleanRequest, error) {
+	report, err := s.Get(reportID)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" || key == model.AnalysisUnknownKey {
+		return nil, fmt.Errorf("该行无法转换为清理条件")
+	}
+	return report.CleanRequestFor(dimension, key, time.Now())
+}
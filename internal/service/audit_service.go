@@ -0,0 +1,136 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"CleanMyEmail/internal/config"
+	"CleanMyEmail/internal/db"
+	"CleanMyEmail/internal/model"
+)
+
+const defaultAuditPageSize = 50 // 删除审计记录默认每页数量
+
+// AuditService 删除审计服务：记录每封被删除的邮件，支持查询和导出
+type AuditService struct{}
+
+// NewAuditService 创建删除审计服务
+func NewAuditService() *AuditService {
+	return &AuditService{}
+}
+
+// SaveDeletedMessages 写入一批删除审计记录（由清理器在每批删除前调用）
+func (s *AuditService) SaveDeletedMessages(messages []*model.DeletedMessage) error {
+	return db.SaveDeletedMessages(messages)
+}
+
+// UpdateDeletedMessagesStatus 更新一批删除审计记录的状态（由清理器在每批删除结束后调用）
+func (s *AuditService) UpdateDeletedMessagesStatus(ids []int64, status string, deletedAt time.Time) error {
+	return db.UpdateDeletedMessagesStatus(ids, status, deletedAt)
+}
+
+// Search 按发件人、主题或 Message-ID 分页查询删除审计记录
+func (s *AuditService) Search(q model.DeletedMessageQuery) (*model.DeletedMessagePage, error) {
+	normalizeAuditQuery(&q)
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = defaultAuditPageSize
+	}
+
+	total, err := db.CountDeletedMessages(&q)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := db.SearchDeletedMessages(&q, q.PageSize, (q.Page-1)*q.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	if messages == nil {
+		messages = []*model.DeletedMessage{}
+	}
+	return &model.DeletedMessagePage{Total: total, Page: q.Page, PageSize: q.PageSize, Messages: messages}, nil
+}
+
+// Export 将符合条件的全部删除审计记录导出到数据目录下的 exports 目录，忽略分页参数
+func (s *AuditService) Export(q model.DeletedMessageQuery, format model.AuditExportFormat) (*model.AuditExportResult, error) {
+	if format == "" {
+		format = model.AuditExportCSV
+	}
+	if format != model.AuditExportCSV && format != model.AuditExportJSON {
+		return nil, fmt.Errorf("不支持的导出格式: %s", format)
+	}
+
+	normalizeAuditQuery(&q)
+	messages, err := db.SearchDeletedMessages(&q, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(config.GetDataDir(), "exports")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建导出目录失败: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("deleted-messages-%s.%s", time.Now().Format("20060102-150405"), format))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建导出文件失败: %w", err)
+	}
+	if format == model.AuditExportJSON {
+		if messages == nil {
+			messages = []*model.DeletedMessage{}
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		err = enc.Encode(messages)
+	} else {
+		err = writeAuditCSV(f, messages)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("写入导出文件失败: %w", err)
+	}
+	return &model.AuditExportResult{Path: path, Count: len(messages)}, nil
+}
+
+// writeAuditCSV 以 CSV 格式写入删除审计记录，带 UTF-8 BOM 以便 Excel 正确识别中文
+func writeAuditCSV(f *os.File, messages []*model.DeletedMessage) error {
+	if _, err := f.WriteString("\ufeff"); err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"deleted_at", "history_id", "account", "folder", "uid", "message_id",
+		"from", "to", "subject", "date", "size", "action", "status"})
+	for _, m := range messages {
+		date := ""
+		if !m.Date.IsZero() {
+			date = m.Date.Format(time.RFC3339)
+		}
+		w.Write([]string{
+			m.DeletedAt.Format(time.RFC3339), strconv.FormatInt(m.HistoryID, 10), m.AccountEmail, m.Folder,
+			strconv.FormatUint(uint64(m.UID), 10), m.MessageID, m.From, m.To, m.Subject, date,
+			strconv.FormatInt(m.Size, 10), m.Action, m.Status,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// normalizeAuditQuery 去掉查询条件两端的空白，Message-ID 去掉尖括号（审计记录中保存的不含尖括号）
+func normalizeAuditQuery(q *model.DeletedMessageQuery) {
+	q.Sender = strings.TrimSpace(q.Sender)
+	q.Subject = strings.TrimSpace(q.Subject)
+	q.MessageID = strings.Trim(strings.TrimSpace(q.MessageID), "<>")
+}
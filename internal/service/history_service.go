@@ -25,22 +25,29 @@ func (s *HistoryService) CreateHistory(req *model.CleanRequest, accountEmail str
 	}
 
 	foldersJSON, _ := json.Marshal(req.Folders)
+	requestJSON, _ := json.Marshal(req)
+	// 排除项单独记录，便于审计
+	var exclusionsJSON sql.NullString
+	if !req.Exclusions.IsEmpty() {
+		data, _ := json.Marshal(req.Exclusions)
+		exclusionsJSON = sql.NullString{String: string(data), Valid: true}
+	}
 	dateRange := ""
 	if req.StartDate != "" {
 		dateRange = req.StartDate + " ~ " + req.EndDate
-	} else {
+	} else if req.EndDate != "" {
 		dateRange = "~ " + req.EndDate
 	}
 
 	result, err := database.Exec(`
 		INSERT INTO clean_history (
-			account_id, account_email, folders, folder_count, date_range,
-			filter_sender, filter_subject, filter_size, filter_read,
-			preview_only, start_time, status
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, req.AccountID, accountEmail, string(foldersJSON), len(req.Folders), dateRange,
-		req.FilterSender, req.FilterSubject, req.FilterSize, req.FilterRead,
-		req.PreviewOnly, time.Now(), "running")
+			account_id, account_email, folders, folder_count, date_range, date_expression,
+			filter_sender, filter_subject, filter_size, filter_read, filter_query,
+			preview_only, start_time, status, request_json, exclusions
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, req.AccountID, accountEmail, string(foldersJSON), len(req.Folders), dateRange, req.DateExpression(),
+		req.FilterSender, req.FilterSubject, req.FilterSize, req.FilterRead, req.FilterQuery,
+		req.PreviewOnly, time.Now(), "running", string(requestJSON), exclusionsJSON)
 	if err != nil {
 		return 0, err
 	}
@@ -64,6 +71,179 @@ func (s *HistoryService) UpdateHistory(id int64, matchedCount, deletedCount int,
 	return err
 }
 
+// SetArchivePath 记录删除前备份的归档目录
+func (s *HistoryService) SetArchivePath(id int64, archivePath string) error {
+	database, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Exec(`UPDATE clean_history SET archive_path = ? WHERE id = ?`, archivePath, id)
+	return err
+}
+
+// SetScheduleID 记录触发本次清理的定时计划
+func (s *HistoryService) SetScheduleID(id, scheduleID int64) error {
+	database, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Exec(`UPDATE clean_history SET schedule_id = ? WHERE id = ?`, scheduleID, id)
+	return err
+}
+
+// SetHistoryStatus 更新历史记录状态
+func (s *HistoryService) SetHistoryStatus(id int64, status string) error {
+	database, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Exec(`UPDATE clean_history SET status = ? WHERE id = ?`, status, id)
+	return err
+}
+
+// SetActiveHistoryStatus 更新仍在执行中（running/paused）的记录的状态，用于暂停和继续
+// 任务已结束的记录不会被修改，避免与清理结束时的状态更新竞争
+func (s *HistoryService) SetActiveHistoryStatus(id int64, status string) error {
+	database, err := db.GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Exec(`UPDATE clean_history SET status = ? WHERE id = ? AND status IN ('running', 'paused')`, status, id)
+	return err
+}
+
+// GetHistoryRequest 获取历史记录保存的原始清理请求
+// 旧版本创建的记录没有保存请求，返回 nil
+func (s *HistoryService) GetHistoryRequest(id int64) (*model.CleanRequest, error) {
+	database, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var requestJSON sql.NullString
+	err = database.QueryRow(`SELECT request_json FROM clean_history WHERE id = ?`, id).Scan(&requestJSON)
+	if err != nil {
+		return nil, err
+	}
+	if !requestJSON.Valid || requestJSON.String == "" {
+		return nil, nil
+	}
+
+	var req model.CleanRequest
+	if err := json.Unmarshal([]byte(requestJSON.String), &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// MarkInterrupted 将仍处于 running 或 paused 状态的记录标记为 interrupted
+// 应在启动时调用：此时不可能有任务在运行，running/paused 状态说明上次应用在清理过程中（或暂停期间）退出
+func (s *HistoryService) MarkInterrupted() (int64, error) {
+	database, err := db.GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := database.Exec(`UPDATE clean_history SET status = 'interrupted' WHERE status IN ('running', 'paused')`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListInterrupted 获取中断的清理任务
+func (s *HistoryService) ListInterrupted() ([]model.InterruptedClean, error) {
+	database, err := db.GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := database.Query(`
+		SELECT id, account_id, account_email, folder_count, start_time, request_json
+		FROM clean_history
+		WHERE status = 'interrupted'
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []model.InterruptedClean
+	for rows.Next() {
+		var item model.InterruptedClean
+		var requestJSON sql.NullString
+		if err := rows.Scan(&item.ID, &item.AccountID, &item.AccountEmail, &item.FolderCount,
+			&item.StartTime, &requestJSON); err != nil {
+			continue
+		}
+		item.Resumable = requestJSON.Valid && requestJSON.String != ""
+		list = append(list, item)
+	}
+	rows.Close()
+
+	// 汇总检查点中的进度
+	for i := range list {
+		checkpoints, err := db.GetCheckpoints(list[i].ID)
+		if err != nil {
+			continue
+		}
+		for _, cp := range checkpoints {
+			list[i].DeletedCount += cp.DeletedCount
+			if cp.Status == "completed" {
+				list[i].CompletedFolders++
+			}
+		}
+	}
+	return list, nil
+}
+
+// FinalizeInterrupted 结束中断的清理任务，按检查点汇总已删除数量并清理检查点
+func (s *HistoryService) FinalizeInterrupted(id int64) error {
+	checkpoints, err := db.GetCheckpoints(id)
+	if err != nil {
+		return err
+	}
+
+	var matchedCount, deletedCount int
+	for _, cp := range checkpoints {
+		matchedCount += cp.MatchedCount
+		deletedCount += cp.DeletedCount
+	}
+
+	if err := s.UpdateHistory(id, matchedCount, deletedCount, "cancelled", "任务中断，已手动结束", 0); err != nil {
+		return err
+	}
+	return db.DeleteCheckpoints(id)
+}
+
+// LoadCheckpoints 获取清理任务的检查点（按文件夹索引）
+func (s *HistoryService) LoadCheckpoints(historyID int64) (map[string]*model.CleanCheckpoint, error) {
+	checkpoints, err := db.GetCheckpoints(historyID)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*model.CleanCheckpoint, len(checkpoints))
+	for _, cp := range checkpoints {
+		result[cp.Folder] = cp
+	}
+	return result, nil
+}
+
+// SaveCheckpoint 保存清理检查点
+func (s *HistoryService) SaveCheckpoint(cp *model.CleanCheckpoint) error {
+	return db.SaveCheckpoint(cp)
+}
+
+// DeleteCheckpoints 删除清理任务的检查点
+func (s *HistoryService) DeleteCheckpoints(historyID int64) error {
+	return db.DeleteCheckpoints(historyID)
+}
+
 // GetHistoryList 获取历史记录列表
 func (s *HistoryService) GetHistoryList(limit, offset int) ([]model.CleanHistoryListItem, error) {
 	database, err := db.GetDB()
@@ -72,8 +252,8 @@ func (s *HistoryService) GetHistoryList(limit, offset int) ([]model.CleanHistory
 	}
 
 	rows, err := database.Query(`
-		SELECT id, account_email, folder_count, date_range, matched_count, deleted_count,
-			   preview_only, duration, status, created_at
+		SELECT id, account_email, folder_count, date_range, COALESCE(date_expression, ''), matched_count, deleted_count,
+			   preview_only, duration, status, COALESCE(schedule_id, 0), created_at
 		FROM clean_history
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
@@ -88,9 +268,9 @@ func (s *HistoryService) GetHistoryList(limit, offset int) ([]model.CleanHistory
 		var item model.CleanHistoryListItem
 		var previewOnly int
 		err := rows.Scan(
-			&item.ID, &item.AccountEmail, &item.FolderCount, &item.DateRange,
+			&item.ID, &item.AccountEmail, &item.FolderCount, &item.DateRange, &item.DateExpr,
 			&item.MatchedCount, &item.DeletedCount, &previewOnly,
-			&item.Duration, &item.Status, &item.CreatedAt,
+			&item.Duration, &item.Status, &item.ScheduleID, &item.CreatedAt,
 		)
 		if err != nil {
 			continue
@@ -112,18 +292,20 @@ func (s *HistoryService) GetHistoryDetail(id int64) (*model.CleanHistory, error)
 	var previewOnly int
 	var endTime sql.NullTime
 	var errorMsg sql.NullString
+	var archivePath sql.NullString
+	var exclusionsJSON sql.NullString
 
 	err = database.QueryRow(`
-		SELECT id, account_id, account_email, folders, folder_count, date_range,
-			   filter_sender, filter_subject, filter_size, filter_read,
+		SELECT id, account_id, account_email, folders, folder_count, date_range, COALESCE(date_expression, ''),
+			   filter_sender, filter_subject, filter_size, filter_read, COALESCE(filter_query, ''),
 			   matched_count, deleted_count, preview_only, start_time, end_time,
-			   duration, status, error_message, created_at
+			   duration, status, error_message, archive_path, COALESCE(schedule_id, 0), exclusions, created_at
 		FROM clean_history WHERE id = ?
 	`, id).Scan(
-		&h.ID, &h.AccountID, &h.AccountEmail, &h.Folders, &h.FolderCount, &h.DateRange,
-		&h.FilterSender, &h.FilterSubject, &h.FilterSize, &h.FilterRead,
+		&h.ID, &h.AccountID, &h.AccountEmail, &h.Folders, &h.FolderCount, &h.DateRange, &h.DateExpr,
+		&h.FilterSender, &h.FilterSubject, &h.FilterSize, &h.FilterRead, &h.FilterQuery,
 		&h.MatchedCount, &h.DeletedCount, &previewOnly, &h.StartTime, &endTime,
-		&h.Duration, &h.Status, &errorMsg, &h.CreatedAt,
+		&h.Duration, &h.Status, &errorMsg, &archivePath, &h.ScheduleID, &exclusionsJSON, &h.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -136,6 +318,15 @@ func (s *HistoryService) GetHistoryDetail(id int64) (*model.CleanHistory, error)
 	if errorMsg.Valid {
 		h.ErrorMessage = errorMsg.String
 	}
+	if archivePath.Valid {
+		h.ArchivePath = archivePath.String
+	}
+	if exclusionsJSON.Valid && exclusionsJSON.String != "" {
+		h.Exclusions = &model.CleanExclusions{}
+		if err := json.Unmarshal([]byte(exclusionsJSON.String), h.Exclusions); err != nil {
+			return nil, err
+		}
+	}
 	return &h, nil
 }
 
@@ -145,6 +336,9 @@ func (s *HistoryService) DeleteHistory(id int64) error {
 	if err != nil {
 		return err
 	}
+	if _, err := database.Exec(`DELETE FROM clean_checkpoints WHERE history_id = ?`, id); err != nil {
+		return err
+	}
 	_, err = database.Exec(`DELETE FROM clean_history WHERE id = ?`, id)
 	return err
 }
@@ -155,6 +349,9 @@ func (s *HistoryService) ClearAllHistory() error {
 	if err != nil {
 		return err
 	}
+	if _, err := database.Exec(`DELETE FROM clean_checkpoints`); err != nil {
+		return err
+	}
 	_, err = database.Exec(`DELETE FROM clean_history`)
 	return err
 }
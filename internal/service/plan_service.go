@@ -0,0 +1,128 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"CleanMyEmail/internal/db"
+	"CleanMyEmail/internal/model"
+)
+
+const planTTL = 24 * time.Hour // 清理计划有效期，超过后需要重新预览
+
+// PlanService 清理计划服务
+type PlanService struct{}
+
+// NewPlanService 创建清理计划服务
+func NewPlanService() *PlanService {
+	return &PlanService{}
+}
+
+// CreateFromPreview 根据预览结果创建清理计划，同时清理已过期的计划
+func (s *PlanService) CreateFromPreview(req *model.CleanRequest, accountEmail string, previewHistoryID int64, folders []*model.CleanPlanFolder) (*model.CleanPlan, error) {
+	now := time.Now()
+	if err := db.DeleteExpiredPlans(now); err != nil {
+		log.Printf("[WARN] 清理过期的清理计划失败: %v", err)
+	}
+
+	plan := &model.CleanPlan{
+		AccountID:        req.AccountID,
+		AccountEmail:     accountEmail,
+		PreviewHistoryID: previewHistoryID,
+		Request:          *req,
+		Folders:          folders,
+		Status:           model.PlanStatusPending,
+		ExpiresAt:        now.Add(planTTL),
+	}
+	plan.Request.PreviewOnly = false
+	for _, f := range folders {
+		plan.TotalCount += f.Count
+	}
+
+	id, err := db.CreatePlan(plan)
+	if err != nil {
+		return nil, fmt.Errorf("保存清理计划失败: %w", err)
+	}
+	return db.GetPlan(id)
+}
+
+// Get 获取清理计划
+func (s *PlanService) Get(id int64) (*model.CleanPlan, error) {
+	plan, err := db.GetPlan(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("清理计划不存在或已过期")
+	}
+	return plan, err
+}
+
+// Prepare 获取待执行的计划，计划已执行或已过期时返回错误
+func (s *PlanService) Prepare(id int64) (*model.CleanPlan, error) {
+	plan, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Status == model.PlanStatusExecuted {
+		return nil, fmt.Errorf("清理计划已执行过，请重新预览")
+	}
+	if plan.IsStale(time.Now()) {
+		return nil, fmt.Errorf("清理计划已过期，请重新预览")
+	}
+	if plan.TotalCount == 0 {
+		return nil, fmt.Errorf("清理计划中没有要删除的邮件")
+	}
+	return plan, nil
+}
+
+// SetExclusions 设置执行计划时排除的邮件、发件人和域名（覆盖之前的设置）
+func (s *PlanService) SetExclusions(id int64, exclusions *model.CleanExclusions) (*model.CleanPlan, error) {
+	plan, err := s.Prepare(id)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions.Normalize()
+	plan.Request.Exclusions = nil
+	if !exclusions.IsEmpty() {
+		plan.Request.Exclusions = exclusions
+	}
+	if err := db.UpdatePlanRequest(id, &plan.Request); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("清理计划已执行过，请重新预览")
+		}
+		return nil, fmt.Errorf("更新清理计划失败: %w", err)
+	}
+	return db.GetPlan(id)
+}
+
+// GetExecuted 获取由指定清理历史执行的计划，该历史不是执行计划产生的（或计划已被清理）时返回 nil
+func (s *PlanService) GetExecuted(historyID int64) (*model.CleanPlan, error) {
+	id, err := db.GetPlanIDByExecutedHistory(historyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db.GetPlan(id)
+}
+
+// Claim 将计划标记为已执行并关联执行的清理历史，计划只能执行一次
+func (s *PlanService) Claim(id, historyID int64) error {
+	if err := db.MarkPlanExecuted(id, historyID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("清理计划已执行过，请重新预览")
+		}
+		return fmt.Errorf("更新清理计划失败: %w", err)
+	}
+	return nil
+}
+
+// Release 撤销 Claim：执行任务未能开始时将计划恢复为待执行，之后可以重新执行
+func (s *PlanService) Release(id, historyID int64) {
+	if err := db.ReleasePlan(id, historyID); err != nil {
+		log.Printf("[WARN] 恢复清理计划状态失败: %v", err)
+	}
+}
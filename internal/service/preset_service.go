@@ -0,0 +1,141 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"CleanMyEmail/internal/db"
+	"CleanMyEmail/internal/email/query"
+	"CleanMyEmail/internal/model"
+)
+
+// PresetService 清理预设服务
+type PresetService struct {
+	historyService *HistoryService
+}
+
+// NewPresetService 创建清理预设服务
+func NewPresetService(historyService *HistoryService) *PresetService {
+	return &PresetService{historyService: historyService}
+}
+
+// List 获取所有预设
+func (s *PresetService) List() ([]*model.CleanPreset, error) {
+	return db.ListPresets()
+}
+
+// Get 获取预设
+func (s *PresetService) Get(id int64) (*model.CleanPreset, error) {
+	preset, err := db.GetPreset(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("预设不存在")
+	}
+	return preset, err
+}
+
+// Create 创建预设
+func (s *PresetService) Create(preset *model.CleanPreset) (*model.CleanPreset, error) {
+	if err := s.validate(preset); err != nil {
+		return nil, err
+	}
+	id, err := db.CreatePreset(preset)
+	if err != nil {
+		return nil, fmt.Errorf("创建预设失败: %w", err)
+	}
+	return db.GetPreset(id)
+}
+
+// Update 更新预设
+func (s *PresetService) Update(preset *model.CleanPreset) (*model.CleanPreset, error) {
+	if _, err := s.Get(preset.ID); err != nil {
+		return nil, err
+	}
+	if err := s.validate(preset); err != nil {
+		return nil, err
+	}
+	if err := db.UpdatePreset(preset); err != nil {
+		return nil, fmt.Errorf("更新预设失败: %w", err)
+	}
+	return db.GetPreset(preset.ID)
+}
+
+// Delete 删除预设
+func (s *PresetService) Delete(id int64) error {
+	return db.DeletePreset(id)
+}
+
+// Apply 将预设应用到账号，返回可直接提交的清理请求模板
+func (s *PresetService) Apply(presetID, accountID int64) (*model.CleanRequest, error) {
+	preset, err := s.Get(presetID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.GetAccountByID(accountID); err != nil {
+		return nil, fmt.Errorf("账号不存在")
+	}
+	return preset.ApplyTo(accountID), nil
+}
+
+// CreateFromHistory 将清理历史中的文件夹和筛选条件保存为预设
+func (s *PresetService) CreateFromHistory(historyID int64, name string) (*model.CleanPreset, error) {
+	history, err := s.historyService.GetHistoryDetail(historyID)
+	if err != nil {
+		return nil, fmt.Errorf("获取历史记录失败: %w", err)
+	}
+
+	preset := &model.CleanPreset{
+		Name:          name,
+		FilterSender:  history.FilterSender,
+		FilterSubject: history.FilterSubject,
+		FilterSize:    history.FilterSize,
+		FilterRead:    history.FilterRead,
+		FilterQuery:   history.FilterQuery,
+	}
+	if err := json.Unmarshal([]byte(history.Folders), &preset.Folders); err != nil {
+		return nil, fmt.Errorf("解析历史记录的文件夹失败: %w", err)
+	}
+	// 新版本的历史记录保存了原始请求，可以取得批大小和并发数
+	if req, err := s.historyService.GetHistoryRequest(historyID); err == nil && req != nil {
+		preset.BatchSize = req.BatchSize
+		preset.AdaptiveBatch = req.AdaptiveBatch
+		preset.MaxConcurrency = req.MaxConcurrency
+		preset.OlderThan = req.OlderThan
+		preset.Between = req.Between
+		preset.DateBasis = req.DateBasis
+	}
+	return s.Create(preset)
+}
+
+// validate 校验预设，名称不能与其他预设重复
+func (s *PresetService) validate(preset *model.CleanPreset) error {
+	preset.Name = strings.TrimSpace(preset.Name)
+	if preset.Name == "" {
+		return fmt.Errorf("请输入预设名称")
+	}
+	if len(preset.Folders) == 0 {
+		return fmt.Errorf("请选择文件夹")
+	}
+	if _, err := query.Parse(preset.FilterQuery, db.GetLocation()); err != nil {
+		return fmt.Errorf("筛选表达式错误: %w", err)
+	}
+	// 校验相对日期，解析结果不保存
+	if err := preset.ApplyTo(0).ResolveDates(time.Now()); err != nil {
+		return err
+	}
+	switch preset.DateBasis {
+	case "", model.DateBasisInternal, model.DateBasisSent:
+	default:
+		return fmt.Errorf("不支持的日期依据: %s", preset.DateBasis)
+	}
+	if preset.BatchSize < 0 || preset.MaxConcurrency < 0 {
+		return fmt.Errorf("批大小和并发数不能为负数")
+	}
+	if existing, err := db.GetPresetByName(preset.Name); err == nil && existing.ID != preset.ID {
+		return fmt.Errorf("预设 %q 已存在", preset.Name)
+	}
+	return nil
+}
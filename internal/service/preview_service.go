@@ -0,0 +1,158 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"CleanMyEmail/internal/model"
+)
+
+const defaultPreviewPageSize = 50 // 预览列表默认每页数量
+
+// previewResult 一次预览的结果
+type previewResult struct {
+	historyID int64
+	createdAt time.Time
+	messages  []*model.PreviewMessage
+	totalSize int64
+}
+
+// PreviewService 预览结果服务，保存每个账号最近一次预览的邮件列表（仅在内存中）
+type PreviewService struct {
+	mu       sync.RWMutex
+	previews map[int64]*previewResult // key: accountID
+}
+
+// NewPreviewService 创建预览结果服务
+func NewPreviewService() *PreviewService {
+	return &PreviewService{previews: make(map[int64]*previewResult)}
+}
+
+// Save 保存账号最近一次预览的邮件列表，覆盖之前的结果
+func (s *PreviewService) Save(accountID, historyID int64, messages []*model.PreviewMessage) {
+	var totalSize int64
+	for _, msg := range messages {
+		totalSize += msg.Size
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.previews[accountID] = &previewResult{
+		historyID: historyID,
+		createdAt: time.Now(),
+		messages:  messages,
+		totalSize: totalSize,
+	}
+}
+
+// Clear 清除账号的预览结果
+func (s *PreviewService) Clear(accountID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.previews, accountID)
+}
+
+// Page 按条件排序并分页获取预览的邮件列表
+func (s *PreviewService) Page(q model.PreviewQuery) (*model.PreviewPage, error) {
+	s.mu.RLock()
+	preview := s.previews[q.AccountID]
+	s.mu.RUnlock()
+	if preview == nil {
+		return nil, fmt.Errorf("没有预览结果，请先预览")
+	}
+
+	messages := preview.messages
+	totalSize := preview.totalSize
+	if q.Folder != "" {
+		messages, totalSize = nil, 0
+		for _, msg := range preview.messages {
+			if msg.Folder == q.Folder {
+				messages = append(messages, msg)
+				totalSize += msg.Size
+			}
+		}
+	}
+
+	// 复制后排序，不影响保存的原始顺序
+	sorted := make([]*model.PreviewMessage, len(messages))
+	copy(sorted, messages)
+	less, err := previewLess(q.SortBy)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if q.SortDesc {
+			return less(sorted[j], sorted[i])
+		}
+		return less(sorted[i], sorted[j])
+	})
+
+	pageSize := q.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPreviewPageSize
+	}
+	page := q.Page
+	if page <= 0 {
+		page = 1
+	}
+	start := min((page-1)*pageSize, len(sorted))
+	end := min(start+pageSize, len(sorted))
+
+	return &model.PreviewPage{
+		AccountID: q.AccountID,
+		HistoryID: preview.historyID,
+		CreatedAt: preview.createdAt,
+		Total:     len(sorted),
+		TotalSize: totalSize,
+		Page:      page,
+		PageSize:  pageSize,
+		Messages:  sorted[start:end],
+	}, nil
+}
+
+// previewLess 返回排序字段的比较函数，相同时按文件夹和 UID 排序保证顺序稳定
+func previewLess(field model.PreviewSortField) (func(a, b *model.PreviewMessage) bool, error) {
+	var cmp func(a, b *model.PreviewMessage) int
+	switch field {
+	case "", model.PreviewSortDate:
+		cmp = func(a, b *model.PreviewMessage) int { return a.Date.Compare(b.Date) }
+	case model.PreviewSortSize:
+		cmp = func(a, b *model.PreviewMessage) int { return compareInt64(a.Size, b.Size) }
+	case model.PreviewSortFrom:
+		cmp = func(a, b *model.PreviewMessage) int {
+			return strings.Compare(strings.ToLower(a.From), strings.ToLower(b.From))
+		}
+	case model.PreviewSortSubject:
+		cmp = func(a, b *model.PreviewMessage) int {
+			return strings.Compare(strings.ToLower(a.Subject), strings.ToLower(b.Subject))
+		}
+	case model.PreviewSortFolder:
+		cmp = func(a, b *model.PreviewMessage) int { return 0 }
+	default:
+		return nil, fmt.Errorf("不支持的排序字段: %s", field)
+	}
+
+	return func(a, b *model.PreviewMessage) bool {
+		if c := cmp(a, b); c != 0 {
+			return c < 0
+		}
+		if a.Folder != b.Folder {
+			return a.Folder < b.Folder
+		}
+		return a.UID < b.UID
+	}, nil
+}
+
+// compareInt64 比较两个整数
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}